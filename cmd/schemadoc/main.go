@@ -0,0 +1,34 @@
+// Command schemadoc generates a file registering the doc comments of a package's types and fields
+// as the descriptions of their schemas, run it with go generate:
+//
+//	//go:generate go run github.com/pindamonhangaba/apiculi/cmd/schemadoc
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/pindamonhangaba/apiculi/schemadoc"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "package directory")
+	out := flag.String("out", "schemadoc_gen.go", "generated file, in the package directory")
+	flag.Parse()
+
+	p, err := schemadoc.Parse(*dir, *out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Create(filepath.Join(*dir, *out))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := schemadoc.Generate(f, p); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+// Command tsgen generates TypeScript type definitions and a fetch based client from an OpenAPI 3.0 JSON document,
+// endpoint.OpenAPI.T() is one whatever the version the API's document is encoded in
+//
+//	tsgen -in openapi.json -out api.ts
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pindamonhangaba/apiculi/tsgen"
+)
+
+func main() {
+	in := flag.String("in", "", "OpenAPI JSON document, stdin if empty")
+	out := flag.String("out", "", "TypeScript file, stdout if empty")
+	flag.Parse()
+
+	var (
+		b   []byte
+		err error
+	)
+	if len(*in) > 0 {
+		b, err = os.ReadFile(*in)
+	} else {
+		b, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// kin-openapi can't load 3.1 schemas, whose types may be lists
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if err := json.Unmarshal(b, &doc); err == nil && strings.HasPrefix(doc.OpenAPI, "3.1") {
+		log.Fatalf("OpenAPI %s documents are not supported, tsgen reads OpenAPI 3.0 ones: encode endpoint.OpenAPI.T() instead", doc.OpenAPI)
+	}
+
+	t, err := openapi3.NewLoader().LoadFromData(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := os.Stdout
+	if len(*out) > 0 {
+		w, err = os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer w.Close()
+	}
+	if err := tsgen.Generate(w, t); err != nil {
+		log.Fatal(err)
+	}
+}
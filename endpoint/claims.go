@@ -0,0 +1,190 @@
+package endpoint
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// ClaimsSource gives claims providers access to the request, whatever the framework
+type ClaimsSource interface {
+	// Value reads a value stored by a middleware: echo.Context.Get, fiber.Ctx.Locals or the request's context
+	Value(key any) any
+	Header(name string) string
+	Cookie(name string) string
+	Query(name string) string
+	// TLS is nil on plain-text connections
+	TLS() *tls.ConnectionState
+}
+
+// ClaimsProvider reads the claims of a request, e.g. from a JWT, a session or a client certificate.
+// Claims are converted to the endpoint's claims type, directly if they have that type or through JSON.
+// Returning nil claims and no error means the request has no claims
+type ClaimsProvider interface {
+	Claims(src ClaimsSource) (any, error)
+}
+
+// ClaimsProviderFunc is a function that implements ClaimsProvider
+type ClaimsProviderFunc func(src ClaimsSource) (any, error)
+
+func (f ClaimsProviderFunc) Claims(src ClaimsSource) (any, error) {
+	return f(src)
+}
+
+// JWTClaims reads the claims of a *jwt.Token stored under key by a JWT middleware,
+// JWTClaims("user") is the default provider
+func JWTClaims(key string) ClaimsProvider {
+	return ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		token, ok := src.Value(key).(*jwt.Token)
+		if !ok || token == nil {
+			return nil, nil
+		}
+		return token.Claims, nil
+	})
+}
+
+// ContextClaims reads claims stored under key by a middleware, e.g. a session loaded from a cookie
+func ContextClaims(key any) ClaimsProvider {
+	return ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		return src.Value(key), nil
+	})
+}
+
+var defaultClaimsProvider = JWTClaims("user")
+
+// SetClaimsProvider sets how the claims of the routes described by op are read, JWTClaims("user") by default,
+// routes registered before it's called use it too, it must not be called while requests are served
+func (op *OpenAPI) SetClaimsProvider(p ClaimsProvider) {
+	op.claims = p
+}
+
+// WithClaimsProvider sets how the route's claims are read, overriding the OpenAPI's provider
+func WithClaimsProvider(p ClaimsProvider) RouteOption {
+	return func(r *RouteDescription) {
+		r.claims = p
+	}
+}
+
+// OptionalClaims lets requests without claims through, the endpoint receives the zero value of its claims type
+func OptionalClaims() RouteOption {
+	return func(r *RouteDescription) {
+		r.OptionalClaims = true
+	}
+}
+
+// resolveClaims reads the claims of a request with the route's provider and checks the route's scopes,
+// claims are required if the route has security or unless C is "any" or the route has OptionalClaims or is Public
+func resolveClaims[C any](rdesc RouteDescription, src ClaimsSource) (cc C, err error) {
+	v, err := rdesc.authorizedClaims(src)
+	if err != nil {
+		if errors.As(err, new(*Error)) {
+			return cc, AsError(err)
+		}
+		return cc, NewError(http.StatusUnauthorized, "invalid claims").Wrap(err)
+	}
+	if noClaims(v) {
+		if !claimsRequired[C](rdesc) {
+			return cc, nil
+		}
+		return cc, NewError(http.StatusUnauthorized, "missing claims")
+	}
+	if c, ok := v.(C); ok {
+		return c, nil
+	}
+	b, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(b, &cc)
+	}
+	if err != nil {
+		return cc, NewError(http.StatusUnauthorized, "unexpected claims type").Wrap(err)
+	}
+	return cc, nil
+}
+
+// claimsRequired tells if requests without claims are answered 401
+func claimsRequired[C any](rdesc RouteDescription) bool {
+	anyClaims := reflect.TypeOf(new(C)).Elem() == reflect.TypeOf(new(any)).Elem()
+	return len(rdesc.Security) > 0 || !(rdesc.OptionalClaims || rdesc.Public || anyClaims)
+}
+
+// noClaims tells if a provider found no claims, nil or a nil pointer
+func noClaims(v any) bool {
+	return v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil())
+}
+
+type httpClaimsSource struct {
+	req *http.Request
+}
+
+func (s httpClaimsSource) Value(key any) any {
+	return s.req.Context().Value(key)
+}
+
+func (s httpClaimsSource) Header(name string) string {
+	return s.req.Header.Get(name)
+}
+
+func (s httpClaimsSource) Cookie(name string) string {
+	c, err := s.req.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func (s httpClaimsSource) Query(name string) string {
+	return s.req.URL.Query().Get(name)
+}
+
+func (s httpClaimsSource) TLS() *tls.ConnectionState {
+	return s.req.TLS
+}
+
+type echoClaimsSource struct {
+	httpClaimsSource
+	c echo.Context
+}
+
+func (s echoClaimsSource) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if v := s.c.Get(k); v != nil {
+			return v
+		}
+	}
+	return s.httpClaimsSource.Value(key)
+}
+
+type fiberClaimsSource struct {
+	c *fiber.Ctx
+}
+
+func (s fiberClaimsSource) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if v := s.c.Locals(k); v != nil {
+			return v
+		}
+	}
+	return s.c.UserContext().Value(key)
+}
+
+func (s fiberClaimsSource) Header(name string) string {
+	return s.c.Get(name)
+}
+
+func (s fiberClaimsSource) Cookie(name string) string {
+	return s.c.Cookies(name)
+}
+
+func (s fiberClaimsSource) Query(name string) string {
+	return s.c.Query(name)
+}
+
+func (s fiberClaimsSource) TLS() *tls.ConnectionState {
+	return s.c.Context().TLSConnectionState()
+}
@@ -0,0 +1,247 @@
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+var pathParamRgx = regexp.MustCompile(`{([^}]+)}`)
+
+// Client calls the endpoints of a remote API
+type Client struct {
+	// URL the routes' paths are appended to, e.g. "https://api.example.com"
+	BaseURL string
+	// http.DefaultClient if nil
+	HTTPClient *http.Client
+	// Before is called with each request before it's sent, e.g. to set its Authorization header
+	Before func(*http.Request) error
+}
+
+// Call sends in to the route p of c's API, with the same types the route's endpoint is declared with:
+//
+//	res, err := endpoint.Call[any, GetParams, ContextQ, any, endpoint.SingleItemData[Collection]](
+//		ctx, client, endpoint.Get("/api/collection/{id}"), endpoint.EndpointInput[any, GetParams, ContextQ, any]{
+//			Params: GetParams{ID: 3},
+//		})
+//
+// P fills the path's "{param}" or ":param" placeholders, Q the query string, fields tagged `in:"header"` or `in:"cookie"`
+// are sent as headers or cookies, B is sent as JSON. Error responses are returned as *Error.
+// Claims are not sent, authenticate the request in c.Before
+func Call[C, P, Q, B any, D dataer](ctx context.Context, c Client, p endpointPath, in EndpointInput[C, P, Q, B]) (res DataResponse[D], err error) {
+	pv, err := encodeValues(in.Params)
+	if err != nil {
+		return res, errors.Wrap(err, "encoding params")
+	}
+	qv, err := encodeValues(in.Query)
+	if err != nil {
+		return res, errors.Wrap(err, "encoding query")
+	}
+
+	path := serveMuxPathToOpenAPIPath(routerPathToOpenAPIPath(p.path))
+	path = pathParamRgx.ReplaceAllStringFunc(path, func(m string) string {
+		vals := lookupValues(pv.values, m[1:len(m)-1])
+		if len(vals) == 0 {
+			err = errors.Errorf("missing path parameter %s", m)
+			return m
+		}
+		return url.PathEscape(vals[0])
+	})
+	if err != nil {
+		return res, err
+	}
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(qv.values) > 0 {
+		u += "?" + url.Values(qv.values).Encode()
+	}
+
+	var body io.Reader
+	if quick_schema.GetSchema[B]() != nil && p.verb != GET {
+		if files, err := fileFields(reflect.TypeOf(new(B)).Elem()); err != nil || len(files) > 0 {
+			return res, errors.New("file uploads are not supported")
+		}
+		b, err := json.Marshal(in.Body)
+		if err != nil {
+			return res, errors.Wrap(err, "encoding body")
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, string(p.verb), u, body)
+	if err != nil {
+		return res, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for _, src := range []paramValues{pv, qv} {
+		for k, vals := range src.header {
+			for _, v := range vals {
+				req.Header.Add(k, v)
+			}
+		}
+		for k, vals := range src.cookies {
+			for _, v := range vals {
+				req.AddCookie(&http.Cookie{Name: k, Value: v})
+			}
+		}
+	}
+	if c.Before != nil {
+		if err := c.Before(req); err != nil {
+			return res, err
+		}
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return res, errors.Wrap(err, "reading response")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var er errorResponse
+		if err := json.Unmarshal(b, &er); err != nil || er.Error.Code == 0 {
+			return res, NewError(resp.StatusCode, http.StatusText(resp.StatusCode)).Wrap(errors.New(string(b)))
+		}
+		return res, er.asError()
+	}
+	if len(b) > 0 {
+		if err := unmarshalJSON(b, &res); err != nil {
+			return res, errors.Wrap(err, "decoding response")
+		}
+	}
+	res.location = resp.Header.Get("Location")
+	return res, nil
+}
+
+// encodeValues is the inverse of decodeValues, it flattens a struct or map into parameter values
+func encodeValues(v any) (src paramValues, err error) {
+	src = paramValues{
+		values:  map[string][]string{},
+		header:  http.Header{},
+		cookies: map[string][]string{},
+	}
+	if v == nil {
+		return src, nil
+	}
+	return src, encodeInto(reflect.ValueOf(v), src)
+}
+
+func encodeInto(v reflect.Value, src paramValues) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			vals, err := formatValue(iter.Value())
+			if err != nil {
+				return err
+			}
+			src.values[fmt.Sprint(iter.Key().Interface())] = vals
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, extra, ok := quick_schema.FieldName(f)
+			if !ok {
+				continue
+			}
+			fv := v.Field(i)
+			if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+				if err := encodeInto(fv, src); err != nil {
+					return err
+				}
+				continue
+			}
+			if has(extra, "omitempty") && fv.IsZero() {
+				continue
+			}
+			vals, err := formatValue(fv)
+			if err != nil {
+				return errors.Wrap(err, name)
+			}
+			if len(vals) == 0 {
+				continue
+			}
+			switch strings.TrimSpace(f.Tag.Get("in")) {
+			case "header":
+				src.header[http.CanonicalHeaderKey(name)] = vals
+			case "cookie":
+				src.cookies[name] = vals
+			default:
+				src.values[name] = vals
+			}
+		}
+	default:
+		return errors.Errorf("unsupported type %s", v.Type().String())
+	}
+	return nil
+}
+
+// formatValue is the inverse of setValue
+func formatValue(v reflect.Value) ([]string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return []string{string(b)}, err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return []string{v.String()}, nil
+	case reflect.Bool:
+		return []string{strconv.FormatBool(v.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())}, nil
+	case reflect.Slice, reflect.Array:
+		vals := []string{}
+		for i := 0; i < v.Len(); i++ {
+			s, err := formatValue(v.Index(i))
+			if err != nil {
+				return nil, errors.Wrapf(err, "item %d", i)
+			}
+			vals = append(vals, s...)
+		}
+		return vals, nil
+	}
+	// structs and maps are sent as JSON
+	b, err := json.Marshal(v.Interface())
+	return []string{string(b)}, err
+}
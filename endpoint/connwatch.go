@@ -0,0 +1,46 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package endpoint
+
+import (
+	"net"
+	"syscall"
+)
+
+// watchConn closes the returned channel if the client closes the connection.
+// The socket is peeked, not read, a request pipelined after the current one stays for the server to read.
+// The connection's deadlines belong to fasthttp, the watch ends on its own once the socket is readable,
+// closed or past the read deadline fasthttp set
+func watchConn(conn net.Conn) (closed <-chan struct{}) {
+	if c, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = c.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	c := make(chan struct{})
+	go func() {
+		eof := false
+		buf := make([]byte, 1)
+		// the netpoller calls back once the socket is readable
+		raw.Read(func(fd uintptr) bool {
+			n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK)
+			switch {
+			case err == syscall.EAGAIN || err == syscall.EINTR:
+				return false
+			case err != nil, n == 0:
+				eof = true
+			}
+			return true
+		})
+		if eof {
+			close(c)
+		}
+	}()
+	return c
+}
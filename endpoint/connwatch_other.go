@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package endpoint
+
+import "net"
+
+// watchConn can't tell when the client closes the connection on this platform, see connwatch.go
+func watchConn(conn net.Conn) (closed <-chan struct{}) {
+	return nil
+}
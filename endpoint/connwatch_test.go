@@ -0,0 +1,81 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestFiberDisconnect(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Add(FiberCtx(
+		Get("/api/slow"),
+		oapi.Route("Slow", "description"),
+		func(ctx context.Context, in EndpointInput[any, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+			close(started)
+			select {
+			case <-ctx.Done():
+				close(cancelled)
+			case <-time.After(5 * time.Second):
+			}
+			return res, nil
+		},
+	))
+	app.Add(FiberCtx(
+		Get("/api/fast"),
+		oapi.Route("Fast", "description"),
+		func(ctx context.Context, in EndpointInput[any, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+			return res, ctx.Err()
+		},
+	))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(conn, "GET /api/slow HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request not handled")
+	}
+	conn.Close()
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("context not cancelled when the client disconnected")
+	}
+
+	// requests pipelined on a kept alive connection are left for the server to read
+	conn, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, strings.Repeat("GET /api/fast HTTP/1.1\r\nHost: localhost\r\n\r\n", 2))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got, buf := "", make([]byte, 4096)
+	for err == nil && strings.Count(got, "HTTP/1.1 200") < 2 {
+		var n int
+		n, err = conn.Read(buf)
+		got += string(buf[:n])
+	}
+	if strings.Count(got, "HTTP/1.1 200") != 2 {
+		t.Errorf("expected two responses, got %q", got)
+	}
+}
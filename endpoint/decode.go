@@ -0,0 +1,224 @@
+package endpoint
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// paramValues holds the raw values of a request's parameters
+type paramValues struct {
+	// path or query values
+	values  map[string][]string
+	header  http.Header
+	cookies map[string][]string
+}
+
+func (src paramValues) lookup(name, in, locationType string) ([]string, string) {
+	switch in {
+	case "header":
+		return src.header.Values(name), in
+	case "cookie":
+		return src.cookies[name], in
+	}
+	return lookupValues(src.values, name), locationType
+}
+
+// requestParams reads the header and cookies of a net/http request
+func requestParams(req *http.Request, values map[string][]string) paramValues {
+	cookies := map[string][]string{}
+	for _, c := range req.Cookies() {
+		cookies[c.Name] = append(cookies[c.Name], c.Value)
+	}
+	return paramValues{
+		values:  values,
+		header:  req.Header,
+		cookies: cookies,
+	}
+}
+
+// decodeValues builds a T from path or query values keyed by field name (as in the schema),
+// strings are coerced into the fields' types and each field that fails to parse is reported.
+// Fields tagged `in:"header"` or `in:"cookie"` are read from the request's header or cookies
+func decodeValues[T any](src paramValues, locationType string) (T, error) {
+	out := new(T)
+	v := reflect.ValueOf(out).Elem()
+	// ignore values if type is "any"
+	if v.Kind() == reflect.Interface {
+		return *out, nil
+	}
+	details := decodeInto(v, src, locationType)
+	if len(details) > 0 {
+		return *out, NewError(http.StatusBadRequest, "invalid request "+locationType, details...)
+	}
+	return *out, nil
+}
+
+func decodeInto(v reflect.Value, src paramValues, locationType string) (details []ErrorDetail) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return []ErrorDetail{
+				Detail("global", "invalidParameter", "unsupported map key type "+v.Type().Key().String()).At("", locationType),
+			}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for k, vals := range src.values {
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(e, vals); err != nil {
+				details = append(details, invalidParameter(k, locationType, err))
+				continue
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), e)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, ok := quick_schema.FieldName(f)
+			if !ok {
+				continue
+			}
+			if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+				details = append(details, decodeInto(v.Field(i), src, locationType)...)
+				continue
+			}
+			vals, lt := src.lookup(name, strings.TrimSpace(f.Tag.Get("in")), locationType)
+			if len(vals) == 0 {
+				continue
+			}
+			if err := setValue(v.Field(i), vals); err != nil {
+				details = append(details, invalidParameter(name, lt, err))
+			}
+		}
+	default:
+		details = append(details, Detail("global", "invalidParameter", "unsupported type "+v.Type().String()).At("", locationType))
+	}
+	return details
+}
+
+// lookupValues finds the values of a field by name, falling back to a case-insensitive match like encoding/json
+func lookupValues(values map[string][]string, name string) []string {
+	if vals, ok := values[name]; ok {
+		return vals
+	}
+	for k, vals := range values {
+		if strings.EqualFold(k, name) {
+			return vals
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Pointer {
+		e := reflect.New(v.Type().Elem())
+		if err := setValue(e.Elem(), vals); err != nil {
+			return err
+		}
+		v.Set(e)
+		return nil
+	}
+	s := vals[0]
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("%q is not a positive integer", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		sl := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i := range vals {
+			if err := setValue(sl.Index(i), vals[i:i+1]); err != nil {
+				return errors.Wrapf(err, "item %d", i)
+			}
+		}
+		v.Set(sl)
+	case reflect.Array:
+		if len(vals) > v.Len() {
+			return errors.Errorf("at most %d values expected", v.Len())
+		}
+		for i := range vals {
+			if err := setValue(v.Index(i), vals[i:i+1]); err != nil {
+				return errors.Wrapf(err, "item %d", i)
+			}
+		}
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return errors.Errorf("unsupported type %s", v.Type().String())
+		}
+		if len(vals) > 1 {
+			v.Set(reflect.ValueOf(vals))
+		} else {
+			v.Set(reflect.ValueOf(s))
+		}
+	default:
+		// structs and maps are sent as JSON
+		if err := json.Unmarshal([]byte(s), v.Addr().Interface()); err != nil {
+			return errors.Wrapf(err, "%q is not a valid %s", s, v.Type().String())
+		}
+	}
+	return nil
+}
+
+// pathValues reads the path parameters declared in P
+func pathValues[P any](param func(name string) string) map[string][]string {
+	m := map[string][]string{}
+	psch := quick_schema.GetSchema[P]()
+	if psch != nil {
+		for _, p := range psch.Children {
+			if len(p.In) > 0 {
+				continue
+			}
+			if v := param(p.Name); len(v) > 0 {
+				m[p.Name] = []string{v}
+			}
+		}
+	}
+	return m
+}
+
+func invalidParameter(name, locationType string, err error) ErrorDetail {
+	return Detail("global", "invalidParameter", fmt.Sprintf("%s: %s", name, err.Error())).At(name, locationType)
+}
@@ -2,15 +2,14 @@ package endpoint
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"strings"
 
-	"github.com/pindamonhangaba/apiculi/quick_schema"
-
 	"github.com/labstack/echo/v4"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
 )
 
@@ -34,53 +33,23 @@ func WithEchoResponseSkipper(s EchoSkipper) echoOptions {
 }
 
 func EchoWithContext[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointWithContext[C, P, Q, B, D, echo.Context], opts ...echoOptions) (string, string, echo.HandlerFunc) {
-	fillOpenAPIRoute[C, P, Q, B, D](endpointPath{
-		verb: p.verb,
-		path: routerPathToOpenAPIPath(p.path),
-	}, d)
-
-	defaultOptions := echoOptions{}
-
-	for _, opt := range opts {
-		defaultOptions.restoreBody = opt.restoreBody
-	}
-
-	return string(p.verb), p.path, func(c echo.Context) error {
-
-		cc, prs, q, b, err := parseBodyEcho[C, P, Q, B, D](p, c, defaultOptions.restoreBody)
-		if err != nil {
-			return err
-		}
-
-		input := EndpointInput[C, P, Q, B]{
-			Claims: cc,
-			Params: prs,
-			Query:  q,
-		}
-		if b != nil {
-			input.Body = *b
-		}
+	return echoWithContext(p, d, next, echoJSON[D], opts...)
+}
 
-		r, err := next(input, c)
-		if err != nil {
-			return err
-		}
-		for _, opt := range opts {
-			if opt.responseSkipper != nil {
-				if (*opt.responseSkipper)(c) {
-					return nil
-				}
-			}
+// EchoWithNoResponse is like EchoWithContext, the handler's data isn't written, only the success status,
+// the route is documented without a response body
+func EchoWithNoResponse[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointWithContext[C, P, Q, B, D, echo.Context], opts ...echoOptions) (string, string, echo.HandlerFunc) {
+	return echoWithContext(p.withoutContent(), d, next, func(c echo.Context, status int, r DataResponse[D]) error {
+		if len(r.location) > 0 {
+			c.Response().Header().Set("Location", r.location)
 		}
-		return c.JSON(http.StatusOK, r)
-	}
+		return c.NoContent(status)
+	}, opts...)
 }
 
-func EchoWithNoResponse[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointWithContext[C, P, Q, B, D, echo.Context], opts ...echoOptions) (string, string, echo.HandlerFunc) {
-	fillOpenAPIRoute[C, P, Q, B, D](endpointPath{
-		verb: p.verb,
-		path: routerPathToOpenAPIPath(p.path),
-	}, d)
+// echoWithContext is the handler of EchoWithContext, respond writes the handler's response
+func echoWithContext[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointWithContext[C, P, Q, B, D, echo.Context], respond func(c echo.Context, status int, r DataResponse[D]) error, opts ...echoOptions) (string, string, echo.HandlerFunc) {
+	rdesc := fillOpenAPIRoute[C, P, Q, B, D](p.withPath(routerPathToOpenAPIPath(p.path)), d)
 
 	defaultOptions := echoOptions{}
 
@@ -90,9 +59,9 @@ func EchoWithNoResponse[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRoute
 
 	return string(p.verb), p.path, func(c echo.Context) error {
 
-		cc, prs, q, b, err := parseBodyEcho[C, P, Q, B, D](p, c, defaultOptions.restoreBody)
+		cc, prs, q, b, err := parseBodyEcho[C, P, Q, B, D](p, rdesc, c, defaultOptions.restoreBody)
 		if err != nil {
-			return err
+			return echoErrJSON(c, err)
 		}
 
 		input := EndpointInput[C, P, Q, B]{
@@ -104,9 +73,13 @@ func EchoWithNoResponse[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRoute
 			input.Body = *b
 		}
 
+		if err := validateInput(input); err != nil {
+			return echoErrJSON(c, err)
+		}
+
 		r, err := next(input, c)
 		if err != nil {
-			return err
+			return echoErrJSON(c, err)
 		}
 		for _, opt := range opts {
 			if opt.responseSkipper != nil {
@@ -115,22 +88,24 @@ func EchoWithNoResponse[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRoute
 				}
 			}
 		}
-		return c.JSON(http.StatusOK, r)
+		return respond(c, p.successStatus(), r)
 	}
 }
 
 func Echo[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next Endpoint[C, P, Q, B, D]) (string, string, echo.HandlerFunc) {
+	return EchoCtx(p, d, next.withContext())
+}
 
-	fillOpenAPIRoute[C, P, Q, B, D](endpointPath{
-		verb: p.verb,
-		path: routerPathToOpenAPIPath(p.path),
-	}, d)
+// EchoCtx is like Echo, the handler receives the request's context
+func EchoCtx[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointCtx[C, P, Q, B, D]) (string, string, echo.HandlerFunc) {
+
+	rdesc := fillOpenAPIRoute[C, P, Q, B, D](p.withPath(routerPathToOpenAPIPath(p.path)), d)
 
 	return string(p.verb), p.path, func(c echo.Context) error {
 
-		cc, prs, q, b, err := parseBodyEcho[C, P, Q, B, D](p, c, false)
+		cc, prs, q, b, err := parseBodyEcho[C, P, Q, B, D](p, rdesc, c, false)
 		if err != nil {
-			return err
+			return echoErrJSON(c, err)
 		}
 
 		input := EndpointInput[C, P, Q, B]{
@@ -142,70 +117,96 @@ func Echo[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, nex
 			input.Body = *b
 		}
 
-		r, err := next(input)
+		if err := validateInput(input); err != nil {
+			return echoErrJSON(c, err)
+		}
+
+		r, err := next(c.Request().Context(), input)
 		if err != nil {
-			return err
+			return echoErrJSON(c, err)
 		}
-		return c.JSON(http.StatusOK, r)
+		return echoJSON(c, p.successStatus(), r)
 	}
 }
 
-func parseBodyEcho[C, P, Q, B any, D dataer](p endpointPath, c echo.Context, restoreBody bool) (cc C, prs P, q Q, b *B, err error) {
-	if _, ok := Find([]string{http.MethodGet, http.MethodConnect, http.MethodHead, http.MethodTrace, http.MethodOptions}, string(p.verb)); !ok {
-		contt := strings.Split(c.Request().Header.Get("Content-Type"), ";")[0]
-		switch contt {
-		case "application/json", "application/x-www-form-urlencoded", "multipart/form-data":
-		default:
-			return cc, prs, q, b, errors.Errorf(`unsupported content-type %s, must be "application/json" or "application/x-www-form-urlencoded"`, contt)
-		}
+func echoJSON[D dataer](c echo.Context, status int, r DataResponse[D]) error {
+	if len(r.location) > 0 {
+		c.Response().Header().Set("Location", r.location)
+	}
+	if status == http.StatusNoContent {
+		return c.NoContent(status)
 	}
+	return c.JSON(status, r)
+}
 
-	// ignore claims if type is "any"
-	if any(*new(C)) != nil {
-		user, ok := c.Get("user").(*jwt.Token)
-		if !ok {
-			return cc, prs, q, b, errors.Errorf("unexpected claims type")
-		}
-		cc, _ = user.Claims.(C)
+// echoErrJSON writes err as a JSONC error response and returns it for the middleware,
+// the HTTPErrorHandler skips the committed response
+func echoErrJSON(c echo.Context, err error) error {
+	var he *echo.HTTPError
+	if !errors.As(err, new(*Error)) && errors.As(err, &he) {
+		err = NewError(he.Code, fmt.Sprint(he.Message)).Wrap(err)
 	}
+	e := AsError(err)
+	if werr := c.JSON(e.Code, e.response()); werr != nil {
+		return werr
+	}
+	return e
+}
 
-	m := map[string]string{}
-	psch := quick_schema.GetSchema[P]()
-	if psch != nil {
-		for _, p := range psch.Children {
-			m[p.Name] = c.Param(p.Name)
+func parseBodyEcho[C, P, Q, B any, D dataer](p endpointPath, rdesc RouteDescription, c echo.Context, restoreBody bool) (cc C, prs P, q Q, b *B, err error) {
+	readBody := rdesc.readsBody(c.Request().ContentLength != 0)
+	if readBody {
+		if err := rdesc.checkContentType(c.Request().Header.Get("Content-Type")); err != nil {
+			return cc, prs, q, b, err
 		}
 	}
-	prs, err = mapToStruct(m, *new(P))
+
+	cc, err = resolveClaims[C](rdesc, echoClaimsSource{httpClaimsSource{req: c.Request()}, c})
 	if err != nil {
-		return cc, prs, q, b, errors.Wrap(err, "params")
+		return cc, prs, q, b, err
 	}
 
-	qMap := map[string]any{}
-	for k, v := range c.Request().URL.Query() {
-		if len(v) > 1 {
-			qMap[k] = v
-		} else if len(v) == 1 {
-			qMap[k] = v[0]
-		}
+	prs, err = decodeValues[P](requestParams(c.Request(), pathValues[P](c.Param)), "path")
+	if err != nil {
+		return cc, prs, q, b, err
 	}
-	q, err = mapToStruct(qMap, *new(Q))
+
+	q, err = decodeValues[Q](requestParams(c.Request(), c.Request().URL.Query()), "query")
 	if err != nil {
-		return cc, prs, q, b, errors.Wrap(err, "query")
+		return cc, prs, q, b, err
+	}
+	if readBody {
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, rdesc.maxBodySize)
 	}
 	origBody := []byte{}
 	if restoreBody {
 		origBody, err = io.ReadAll(c.Request().Body)
 		if err != nil {
-			return cc, prs, q, b, errors.Wrap(err, "duplicating body")
+			return cc, prs, q, b, badRequest(err, "body")
 		}
 		c.Request().Body = io.NopCloser(bytes.NewBuffer(origBody))
 	}
 	b = new(B)
-	if has([]httpVerb{PUT, POST, DELETE, PATCH}, p.verb) {
-		err = c.Bind(b)
-		if err != nil {
-			return cc, prs, q, b, errors.Wrap(err, "body")
+	if readBody {
+		if strings.HasPrefix(c.Request().Header.Get("Content-Type"), "multipart/form-data") {
+			form, err := c.MultipartForm()
+			if err != nil {
+				return cc, prs, q, b, badRequest(err, "body")
+			}
+			b, err = decodeMultipart[B](form)
+			if err != nil {
+				return cc, prs, q, b, err
+			}
+		} else if hasOneOf(reflect.TypeOf(b).Elem()) {
+			err = decodeJSON(c.Request().Body, b)
+			if err != nil {
+				return cc, prs, q, b, badRequest(err, "body")
+			}
+		} else {
+			err = c.Bind(b)
+			if err != nil {
+				return cc, prs, q, b, badRequest(err, "body")
+			}
 		}
 	}
 	if restoreBody {
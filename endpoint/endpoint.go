@@ -1,8 +1,14 @@
 package endpoint
 
+//go:generate go run github.com/pindamonhangaba/apiculi/cmd/schemadoc
+
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -45,6 +51,18 @@ type DataResponse[T dataer] struct {
 	// Client sets this value and server echos data in the response
 	Context string `json:"context,omitempty"`
 	Data    T      `json:"data"`
+
+	location string
+}
+
+// SetLocation sets the Location header of the response, e.g. the URL of a created resource
+func (r *DataResponse[T]) SetLocation(url string) {
+	r.location = url
+}
+
+// Location is the Location header of the response
+func (r DataResponse[T]) Location() string {
+	return r.location
 }
 
 type dataer interface {
@@ -84,7 +102,7 @@ func (d SingleItemData[T]) data() {}
 /* example Schemaer
 func (d SingleItemData[T]) Schema() SchemaRepo {
 	s := quick_schema.GetSchema[SingleItemData[T]]()
-	r := buildSchemaRepo(*s)
+	r := buildSchemaRepo(*s, ReadableSchemaName)
 	// don't do anything if "item" type is something like "any"
 	if r.Start.Properties["item"] != nil {
 		itemSchema := r.Repo[r.Start.Properties["item"].Value.Title]
@@ -163,26 +181,78 @@ func makeNameFromRoute(s string) string {
 }
 
 type Endpoint[C, P, Q, B any, D dataer] func(EndpointInput[C, P, Q, B]) (DataResponse[D], error)
+
+// EndpointCtx is an Endpoint that also receives the request context,
+// adapters fill it from the framework's request so handlers see cancellation, deadlines and trace spans
+type EndpointCtx[C, P, Q, B any, D dataer] func(context.Context, EndpointInput[C, P, Q, B]) (DataResponse[D], error)
 type EndpointWithContext[C, P, Q, B any, D dataer, Context any] func(EndpointInput[C, P, Q, B], Context) (DataResponse[D], error)
 
+func (e Endpoint[C, P, Q, B, D]) withContext() EndpointCtx[C, P, Q, B, D] {
+	return func(_ context.Context, in EndpointInput[C, P, Q, B]) (DataResponse[D], error) {
+		return e(in)
+	}
+}
+
 type RouteDescription struct {
 	Title       string
 	Description string
 	Tag         string
+	// HTTP status codes of the errors the route may return, besides the default ones
+	Errors []int
+	// Requests without claims are let through
+	OptionalClaims bool
+	// Alternative security requirements, one of them must be met
+	Security []SecurityRequirement
+	// The route is not authenticated
+	Public bool
+
+	claims ClaimsProvider
+	// the OpenAPI's claims providers are read when requests are handled
+	api     *OpenAPI
+	schemas *schemaRegistry
+	// content-types of the request bodies the route takes
+	contentTypes []string
+	maxBodySize  int64
+}
+
+// RouteOption customizes how a route is described
+type RouteOption func(*RouteDescription)
+
+// WithErrors documents error status codes the route may return,
+// parse (400), unsupported content-type (415), validation (422) and internal (500) errors are documented by default
+func WithErrors(codes ...int) RouteOption {
+	return func(r *RouteDescription) {
+		r.Errors = append(r.Errors, codes...)
+	}
+}
+
+func (r RouteDescription) with(opts []RouteOption) RouteDescription {
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
 }
 
 type OpenAPIRouteDescriber func(func(RouteDescription, *openapi3.T))
 
 type OpenAPI struct {
-	t openapi3.T
+	t      openapi3.T
+	claims ClaimsProvider
+	// claims providers of the security schemes
+	schemes map[string]ClaimsProvider
+	schemas *schemaRegistry
+	// OpenAPI version of the encoded document, see SetVersion
+	version string
 }
 
-func (op *OpenAPI) Route(title, description string) OpenAPIRouteDescriber {
+func (op *OpenAPI) Route(title, description string, opts ...RouteOption) OpenAPIRouteDescriber {
 	return func(f func(RouteDescription, *openapi3.T)) {
 		f(RouteDescription{
 			Title:       title,
 			Description: description,
-		}, &op.t)
+			api:         op,
+			schemas:     op.schemas,
+		}.with(opts), &op.t)
 	}
 }
 func (op *OpenAPI) RouteGroup(name string, description ...string) OpenAPIRouteGroup {
@@ -212,9 +282,6 @@ func (op *OpenAPI) Describe(description string) {
 }
 
 func (op *OpenAPI) AddServer(url, description string) *openapi3.T {
-	if op.t.Components.SecuritySchemes == nil {
-		op.t.Components.SecuritySchemes = openapi3.SecuritySchemes{}
-	}
 	op.t.Servers = append(op.t.Servers, &openapi3.Server{
 		URL:         url,
 		Description: description,
@@ -222,48 +289,79 @@ func (op *OpenAPI) AddServer(url, description string) *openapi3.T {
 	return &op.t
 }
 
+// AddJWTBearerAuth registers JWT bearer tokens, their claims are read by the OpenAPI's claims provider
 func (op *OpenAPI) AddJWTBearerAuth(name string) *openapi3.T {
-	if op.t.Servers == nil {
-		op.t.Servers = openapi3.Servers{}
-	}
-	op.t.Components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{
-		Value: openapi3.NewJWTSecurityScheme(),
-	}
-	return &op.t
+	return op.addSecurityScheme(name, openapi3.NewJWTSecurityScheme(), nil)
 }
 
 func NewOpenAPI(title, version string) OpenAPI {
 	comp := openapi3.NewComponents()
 	return OpenAPI{
 		t: openapi3.T{
-			OpenAPI: "3.0.0",
+			OpenAPI: OpenAPI30,
 			Info: &openapi3.Info{
 				Title:   title,
 				Version: version,
 			},
 			Components: &comp,
 		},
+		schemas: newSchemaRegistry(),
 	}
 }
 
 type OpenAPIRouteGroup struct {
 	op    *OpenAPI
 	group string
+	opts  []RouteOption
 }
 
-func (g *OpenAPIRouteGroup) Route(title, description string) OpenAPIRouteDescriber {
+func (g *OpenAPIRouteGroup) Route(title, description string, opts ...RouteOption) OpenAPIRouteDescriber {
 	return func(f func(RouteDescription, *openapi3.T)) {
-		f(RouteDescription{
+		r := RouteDescription{
 			Title:       title,
 			Description: description,
 			Tag:         g.group,
-		}, &g.op.t)
+			api:         g.op,
+			schemas:     g.op.schemas,
+		}.with(g.opts)
+		// the route's security replaces the group's
+		if own := (RouteDescription{}).with(opts); own.Public || len(own.Security) > 0 {
+			r.Public, r.Security = false, nil
+		}
+		f(r.with(opts), &g.op.t)
 	}
 }
 
 type endpointPath struct {
-	verb httpVerb
-	path string
+	verb   httpVerb
+	path   string
+	status int
+	// the response has no body, whatever the status
+	noContent bool
+}
+
+// WithStatus sets the HTTP status code of the route's success response, 200 by default.
+// A 204 No Content response has no body, 201 Created responses should set its location with DataResponse.SetLocation
+func (p endpointPath) WithStatus(code int) endpointPath {
+	p.status = code
+	return p
+}
+
+func (p endpointPath) withPath(path string) endpointPath {
+	p.path = path
+	return p
+}
+
+func (p endpointPath) withoutContent() endpointPath {
+	p.noContent = true
+	return p
+}
+
+func (p endpointPath) successStatus() int {
+	if p.status == 0 {
+		return http.StatusOK
+	}
+	return p.status
 }
 
 type httpVerb string
@@ -277,38 +375,46 @@ const (
 )
 
 func Get(path string) endpointPath {
-	return endpointPath{GET, path}
+	return endpointPath{verb: GET, path: path}
 }
 func Post(path string) endpointPath {
-	return endpointPath{POST, path}
+	return endpointPath{verb: POST, path: path}
 }
 func Put(path string) endpointPath {
-	return endpointPath{PUT, path}
+	return endpointPath{verb: PUT, path: path}
 }
 func Patch(path string) endpointPath {
-	return endpointPath{PATCH, path}
+	return endpointPath{verb: PATCH, path: path}
 }
 func Delete(path string) endpointPath {
-	return endpointPath{DELETE, path}
+	return endpointPath{verb: DELETE, path: path}
 }
 
-func fillOpenAPIRoute[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber) {
+// fillOpenAPIRoute documents the route, returns its description for the adapters
+func fillOpenAPIRoute[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber) (route RouteDescription) {
 	d(func(rdesc RouteDescription, swag *openapi3.T) {
-		prepo, err := makeParams[P]("path")
+		route = rdesc
+		schemas := rdesc.schemas
+		if schemas == nil {
+			schemas = newSchemaRegistry()
+		}
+		prepo, err := makeParams[P]("path", schemas.name)
 		if err != nil {
 			panic(errors.Wrap(err, "bad api data"))
 		}
 		params := openapi3.Parameters{}
 		for param, pv := range prepo {
-			err := validatePathParamVar(p.path, param)
-			if err != nil {
-				panic(errors.Wrap(err, "bad param data"))
+			if pv.Value.In == openapi3.ParameterInPath {
+				err := validatePathParamVar(p.path, param)
+				if err != nil {
+					panic(errors.Wrap(err, "bad param data"))
+				}
 			}
 			pv.Ref = ""
 			params = append(params, pv)
 		}
 
-		prepo, err = makeParams[Q]("query")
+		prepo, err = makeParams[Q]("query", schemas.name)
 		if err != nil {
 			panic(errors.Wrap(err, "bad api data"))
 		}
@@ -316,34 +422,52 @@ func fillOpenAPIRoute[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDe
 			pv.Ref = ""
 			params = append(params, pv)
 		}
-
-		if swag.Components.Schemas == nil {
-			swag.Components.Schemas = openapi3.Schemas{}
-		}
+		sort.SliceStable(params, func(i, j int) bool {
+			a, b := params[i].Value, params[j].Value
+			if a.In != b.In {
+				return paramsOrder[a.In] < paramsOrder[b.In]
+			}
+			return a.Name < b.Name
+		})
 
 		bodyTypeNodeSchema := quick_schema.GetSchema[B]()
 		var requestBody *openapi3.RequestBody
+		var files map[string]fileRules
 		// ignore the request body if type is "any"
 		if bodyTypeNodeSchema != nil {
-			bodyRepo := buildSchemaRepo(*bodyTypeNodeSchema)
+			bodyRepo := schemas.build(*bodyTypeNodeSchema)
 
+			files, err = fileFields(reflect.TypeOf(new(B)).Elem())
+			if err != nil {
+				panic(errors.Wrap(err, "bad body data"))
+			}
 			reqContent := openapi3.NewContentWithJSONSchema(bodyRepo.Start)
 			reqContent["application/x-www-form-urlencoded"] = openapi3.NewMediaType().WithSchema(bodyRepo.Start)
-			reqContent["multipart/form-data"] = openapi3.NewMediaType().WithSchema(bodyRepo.Start)
+			// files can only be uploaded as multipart/form-data
+			if len(files) > 0 {
+				reqContent = openapi3.Content{}
+			}
+			reqContent["multipart/form-data"] = multipartEncoding(openapi3.NewMediaType().WithSchema(bodyRepo.Start), files)
 			requestBody = &openapi3.RequestBody{
 				Description: "Request data",
 				Content:     reqContent,
 			}
-			for n, val := range bodyRepo.Repo {
-				if val == nil {
-					panic("unexpected nil bodySchema")
-				}
-				swag.Components.Schemas[n] = openapi3.NewSchemaRef("", val)
+			if err := schemas.register(swag, bodyRepo); err != nil {
+				panic(errors.Wrap(err, "bad body data"))
 			}
 		}
+		// routes whose body is "any" don't read it, files can only be uploaded as multipart/form-data
+		switch {
+		case bodyTypeNodeSchema == nil, p.verb == GET:
+		case len(files) > 0:
+			route.contentTypes = []string{"multipart/form-data"}
+		default:
+			route.contentTypes = bodyContentTypes
+		}
+		route.maxBodySize = maxBodySize(files)
 
 		responseNodeSchema := quick_schema.GetSchema[DataResponse[D]]()
-		responseRepo := buildSchemaRepo(*responseNodeSchema)
+		responseRepo := schemas.build(*responseNodeSchema)
 		resp := new(D)
 		if c, ok := interface{}(resp).(Schemaer); ok {
 			responseRepo = c.Schema()
@@ -359,12 +483,60 @@ func fillOpenAPIRoute[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDe
 			Description: &desc,
 			Content:     openapi3.NewContentWithJSONSchema(responseRepo.Start),
 		}
+		status := p.successStatus()
+		if p.noContent {
+			response.Content = nil
+		}
+		switch status {
+		case http.StatusNoContent:
+			response.Content = nil
+		case http.StatusCreated:
+			response.Headers = openapi3.Headers{
+				"Location": &openapi3.HeaderRef{
+					Value: &openapi3.Header{
+						Parameter: openapi3.Parameter{
+							Description: "URL of the created resource",
+							Schema:      openapi3.NewStringSchema().NewRef(),
+						},
+					},
+				},
+			}
+		}
+
+		if err := schemas.register(swag, responseRepo); err != nil {
+			panic(errors.Wrap(err, "bad response data"))
+		}
 
-		for n, val := range responseRepo.Repo {
-			if val == nil {
-				panic("unexpected nil responseSchema")
+		responses := openapi3.Responses{
+			strconv.Itoa(status): &openapi3.ResponseRef{
+				//Ref:   "#/components/responses/someResponse",
+				Value: response,
+			},
+		}
+		security, err := rdesc.securityRequirements(swag)
+		if err != nil {
+			panic(errors.Wrap(err, "bad security data"))
+		}
+		declaredErrors := rdesc.Errors
+		if claimsRequired[C](rdesc) {
+			declaredErrors = append(declaredErrors, http.StatusUnauthorized)
+		}
+		if rdesc.scoped() {
+			declaredErrors = append(declaredErrors, http.StatusForbidden)
+		}
+		hasInput := len(params) > 0 || bodyTypeNodeSchema != nil
+		validatedP, validatedQ, validatedB := checkConstraintTags[P](), checkConstraintTags[Q](), checkConstraintTags[B]()
+		errRef := addErrorSchema(swag, schemas)
+		// files breaking their limits are answered 422 too
+		validated := validatedP || validatedQ || validatedB || len(files) > 0
+		for _, code := range routeErrors(hasInput, len(route.contentTypes) > 0, validated, declaredErrors) {
+			desc := http.StatusText(code)
+			responses[strconv.Itoa(code)] = &openapi3.ResponseRef{
+				Value: &openapi3.Response{
+					Description: &desc,
+					Content:     openapi3.NewContentWithJSONSchemaRef(openapi3.NewSchemaRef(errRef, nil)),
+				},
 			}
-			swag.Components.Schemas[n] = openapi3.NewSchemaRef("", val)
 		}
 
 		op := &openapi3.Operation{
@@ -372,13 +544,8 @@ func fillOpenAPIRoute[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDe
 			Description: rdesc.Description,
 			OperationID: toCamelCase(rdesc.Title),
 			Parameters:  params,
-			Responses: openapi3.Responses{
-				"200": &openapi3.ResponseRef{
-					//Ref:   "#/components/responses/someResponse",
-					Value: response,
-				},
-			},
-			Security: openapi3.NewSecurityRequirements().With(openapi3.NewSecurityRequirement().Authenticate("bearerAuth", "something else")),
+			Responses:   responses,
+			Security:    security,
 		}
 		if requestBody != nil {
 			op.RequestBody = &openapi3.RequestBodyRef{
@@ -410,40 +577,104 @@ func fillOpenAPIRoute[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDe
 		}
 		swag.Paths[p.path] = pitem
 	})
+	return route
 }
 
-func makeParams[T any](in string) (map[string]*openapi3.ParameterRef, error) {
+// addErrorSchema adds the JSONC error envelope to the document's schemas, returns its ref
+func addErrorSchema(swag *openapi3.T, schemas *schemaRegistry) string {
+	r := schemas.build(*quick_schema.GetSchema[errorResponse]())
+	if err := schemas.register(swag, r); err != nil {
+		panic(errors.Wrap(err, "bad error data"))
+	}
+	return "#/components/schemas/" + r.Start.Title
+}
+
+var bodyContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data"}
+
+// readsBody tells if the request's body is decoded, the route takes a body and the request sent one
+func (r RouteDescription) readsBody(sent bool) bool {
+	return sent && len(r.contentTypes) > 0
+}
+
+// checkContentType answers 415 to request bodies whose content-type the route doesn't take
+func (r RouteDescription) checkContentType(contentType string) error {
+	contt := strings.Split(contentType, ";")[0]
+	if !has(r.contentTypes, contt) {
+		return unsupportedContentType(contt, r.contentTypes)
+	}
+	return nil
+}
+
+// routeErrors lists, sorted, the error status codes a route may return
+func routeErrors(hasInput, hasBody, validated bool, declared []int) []int {
+	codes := []int{http.StatusInternalServerError}
+	if hasInput {
+		codes = append(codes, http.StatusBadRequest)
+	}
+	if validated {
+		codes = append(codes, http.StatusUnprocessableEntity)
+	}
+	if hasBody {
+		codes = append(codes, http.StatusUnsupportedMediaType)
+	}
+	for _, c := range declared {
+		if !has(codes, c) {
+			codes = append(codes, c)
+		}
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+var paramsOrder = map[string]int{
+	openapi3.ParameterInPath:   0,
+	openapi3.ParameterInQuery:  1,
+	openapi3.ParameterInHeader: 2,
+	openapi3.ParameterInCookie: 3,
+}
+
+// makeParams documents T's fields as parameters in "in",
+// fields tagged `in:"header"` or `in:"cookie"` are documented as header or cookie parameters
+func makeParams[T any](in string, name SchemaNameFunc) (map[string]*openapi3.ParameterRef, error) {
 	n := quick_schema.GetSchema[T]()
 	if n == nil {
 		return nil, nil
 	}
-	r := buildSchemaRepo(*n)
-	sch := r.Start
-	repo := r.Repo
+	repo := buildSchemaRepo(*n, name)
+	if repo.err != nil {
+		return nil, repo.err
+	}
+	sch := repo.Start
 
 	if sch.Type != "object" {
 		return nil, errors.Errorf("parameter's type must be a object, is \"%s\"", sch.Type)
 	}
 
+	ins := map[string]string{}
+	for _, c := range n.Children {
+		if len(c.In) > 0 {
+			if _, ok := paramsOrder[c.In]; !ok {
+				return nil, errors.Errorf("parameter \"%s\" can't be in \"%s\"", c.Name, c.In)
+			}
+			ins[c.Name] = c.In
+		}
+	}
+
 	params := []*openapi3.Parameter{}
 	for pname, p := range sch.Properties {
-		required := has(sch.Required, pname)
+		pin := in
+		if v, ok := ins[pname]; ok {
+			pin = v
+		}
+		required := has(sch.Required, pname) || pin == openapi3.ParameterInPath
 
 		pram := &openapi3.Parameter{
 			Description: p.Value.Description,
 			Name:        pname,
-			In:          in,
+			In:          pin,
 			Required:    required,
 			Schema:      p,
 		}
-		for n, r := range repo {
-			if len(strings.TrimSpace(n)) > 0 && n == p.Value.Title {
-				pram.Schema = &openapi3.SchemaRef{
-					Ref:   "#/components/schemas/" + n,
-					Value: r,
-				}
-			}
-		}
 		params = append(params, pram)
 	}
 
@@ -457,81 +688,170 @@ func makeParams[T any](in string) (map[string]*openapi3.ParameterRef, error) {
 	return prepo, nil
 }
 
-func makeRefableName(pkg, typ, name string) string {
-	if pkg == "" {
-		return name
+// buildSchemaRepo converts a node into a schema, named struct types are collected in the repo
+// as components, named by name
+func buildSchemaRepo(n quick_schema.Node, name SchemaNameFunc) SchemaRepo {
+	r := SchemaRepo{
+		Repo:  map[string]*openapi3.Schema{},
+		types: map[string]quick_schema.Node{},
 	}
-	if pkg != "" && typ != "" {
-		return strings.Join(notempty([]string{strings.ReplaceAll(strings.ReplaceAll(pkg, "/", "_"), ".", "_"), typ}), "_")
+	var schemafy func(n quick_schema.Node) *openapi3.Schema
+	// items of arrays and values of maps, named structs are referenced
+	itemSchema := func(item quick_schema.Node) *openapi3.SchemaRef {
+		if item.Format == "pointer" && len(item.Children) == 1 {
+			item = item.Children[0]
+		}
+		ps := schemafy(item)
+		ref := ""
+		if nname := componentName(item, name); (ps.Type == "object" || item.Recursive) && len(nname) > 0 {
+			ref = "#/components/schemas/" + nname
+		}
+		return openapi3.NewSchemaRef(ref, ps)
 	}
-	return strings.Join(notempty([]string{strings.ReplaceAll(strings.ReplaceAll(pkg, "/", "_"), ".", "_"), typ, name}), "_")
-}
-
-func notempty(s []string) (res []string) {
-	for _, v := range s {
-		if v == "" {
-			continue
+	// named types are collected as components
+	define := func(n quick_schema.Node, s *openapi3.Schema) {
+		nname := componentName(n, name)
+		if len(nname) == 0 {
+			return
+		}
+		s.Title = nname
+		t, ok := r.types[nname]
+		switch {
+		case !ok:
+			r.Repo[nname] = s
+			r.types[nname] = n
+		case !t.SameType(n) && r.err == nil:
+			r.err = schemaCollision(nname, t, n)
 		}
-		res = append(res, v)
 	}
-	return res
-}
-
-func buildSchemaRepo(n quick_schema.Node) SchemaRepo {
-	repo := map[string]*openapi3.Schema{}
-	var schemafy func(n quick_schema.Node) *openapi3.Schema
 	schemafy = func(n quick_schema.Node) *openapi3.Schema {
-		nname := makeRefableName(n.Package, n.Type, n.Name)
 		s := openapi3.NewSchema()
-		s.Title = nname
+		s.Title = n.Name
 		s.Type = n.Format
 		s.Format = n.Type
 		s.Example = n.Example
 		s.Description = n.Description
 		s.Nullable = n.Omitempty
+		applyConstraints(s, n.Constraints)
+
+		if n.Format == "complex" && r.err == nil {
+			r.err = errors.Errorf("\"%s\" is a complex number, which can't be encoded as JSON", n.Name)
+		}
+
+		if n.Format == "oneOf" {
+			s.Type, s.Format = "", ""
+			s.Discriminator = &openapi3.Discriminator{
+				PropertyName: n.Discriminator,
+				Mapping:      map[string]string{},
+			}
+			for _, c := range n.Children {
+				ref := "#/components/schemas/" + componentName(c, name)
+				s.OneOf = append(s.OneOf, openapi3.NewSchemaRef(ref, schemafy(c)))
+				s.Discriminator.Mapping[c.Name] = ref
+			}
+			return s
+		}
+
+		// the schema of a type nested in itself is referenced, registered by the enclosing node
+		if n.Recursive {
+			s.Title = componentName(n, name)
+			return s
+		}
 
 		if s.Type == "object" {
 			s.Properties = make(openapi3.Schemas)
 			for _, p := range n.Children {
-				pname := "#/components/schemas/" + makeRefableName(p.Package, "", p.Name)
 				ps := schemafy(p)
+				ref := recursiveRef(p, name)
+				required := p.Constraints != nil && p.Constraints.Required
 				if p.Format == "pointer" && len(p.Children) == 1 {
 					ps = schemafy(p.Children[0])
+					ref = recursiveRef(p.Children[0], name)
+					applyConstraints(ps, p.Constraints)
 					ps.Nullable = true
 				} else {
-					if !p.Omitempty {
-						s.Required = append(s.Required, p.Name)
-					}
+					required = required || !p.Omitempty
 				}
-				if ps.Format != "object" {
-					pname = ""
+				if required {
+					s.Required = append(s.Required, p.Name)
 				}
-				sr := openapi3.NewSchemaRef(pname, ps)
-				s.Properties[p.Name] = sr
-			}
-			_, ok := repo[nname]
-			if !ok {
-				repo[nname] = s
+				s.Properties[p.Name] = openapi3.NewSchemaRef(ref, ps)
 			}
-		} else if (s.Type == "array" || s.Type == "slice") && len(n.Children) == 1 {
+			define(n, s)
+		} else if s.Type == "array" || s.Type == "slice" {
 			s.Type = "array"
-			ps := schemafy(n.Children[0])
-			name := ""
-			if ps.Type == "object" || (s.Type == "array" || s.Type == "slice") {
-				name = makeRefableName(n.Children[0].Package, n.Children[0].Type, n.Children[0].Name)
+			s.Items = openapi3.NewSchemaRef("", openapi3.NewSchema())
+			if len(n.Children) == 1 {
+				s.Items = itemSchema(n.Children[0])
 			}
-			if len(strings.TrimSpace(name)) > 0 {
-				name = "#/components/schemas/" + name
+			// named slices nested in themselves
+			define(n, s)
+		} else if s.Type == "map" {
+			s.Type = "object"
+			if len(n.Children) == 1 {
+				s.AdditionalProperties.Schema = itemSchema(n.Children[0])
+			}
+			// OpenAPI 3.0 has no propertyNames
+			if len(n.KeyPattern) > 0 {
+				s.Extensions = map[string]interface{}{
+					"x-propertyNames": map[string]interface{}{"type": "string", "pattern": n.KeyPattern},
+				}
 			}
-			s.Items = openapi3.NewSchemaRef(name, ps)
+			define(n, s)
 		}
 
 		return s
 	}
-	sch := schemafy(n)
-	return SchemaRepo{
-		Start: sch,
-		Repo:  repo,
+	r.Start = schemafy(n)
+	return r
+}
+
+// recursiveRef is the reference to the component schema of a type nested in itself, empty for other nodes
+func recursiveRef(n quick_schema.Node, name SchemaNameFunc) string {
+	if !n.Recursive {
+		return ""
+	}
+	return "#/components/schemas/" + componentName(n, name)
+}
+
+// componentName is the name of the node's component schema, empty for unnamed types
+func componentName(n quick_schema.Node, name SchemaNameFunc) string {
+	if len(n.SchemaName) == 0 {
+		return ""
+	}
+	return name(n)
+}
+
+func applyConstraints(s *openapi3.Schema, c *quick_schema.Constraints) {
+	if c == nil {
+		return
+	}
+	if c.Minimum != nil {
+		s.Min = c.Minimum
+	}
+	if c.Maximum != nil {
+		s.Max = c.Maximum
+	}
+	if c.MinLength != nil {
+		s.MinLength = *c.MinLength
+	}
+	if c.MaxLength != nil {
+		s.MaxLength = c.MaxLength
+	}
+	if len(c.Pattern) > 0 {
+		s.Pattern = c.Pattern
+	}
+	if len(c.Enum) > 0 {
+		s.Enum = c.Enum
+	}
+	if len(c.Format) > 0 {
+		s.Format = c.Format
+	}
+	if c.MinItems != nil {
+		s.MinItems = *c.MinItems
+	}
+	if c.MaxItems != nil {
+		s.MaxItems = c.MaxItems
 	}
 }
 
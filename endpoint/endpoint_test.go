@@ -1,8 +1,20 @@
 package endpoint
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/pindamonhangaba/apiculi/quick_schema"
@@ -10,7 +22,12 @@ import (
 	"github.com/yudai/gojsondiff/formatter"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
 	"github.com/lib/pq"
+	"github.com/pkg/errors"
 )
 
 type TT = EndpointInput[struct {
@@ -52,7 +69,7 @@ func TestFiber(t *testing.T) {
 
 	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: FiberErrorHandler})
 
 	app.Add(Fiber(
 		Get("/api/endpoint/:id"),
@@ -85,7 +102,7 @@ func TestFiber(t *testing.T) {
 
 func TestParams(t *testing.T) {
 
-	expectedJSON := []byte(`{"/query-param-test":{"post":{"parameters":[{"in":"query","name":"SomeValue","required":true,"schema":{"format":"string","title":"SomeValue","type":"string"}},{"in":"query","name":"AnotherValue","required":true,"schema":{"items":{"format":"int","type":"number"},"title":"AnotherValue","type":"array"}},{"in":"query","name":"Props","required":true,"schema":{"format":"testParam","properties":{"ParamProp":{"format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"Props","type":"object"}}],"responses":null}}}`)
+	expectedJSON := []byte(`{"/query-param-test":{"post":{"parameters":[{"in":"query","name":"AnotherValue","required":true,"schema":{"example":"","items":{"example":"","format":"int64","type":"integer"},"title":"AnotherValue","type":"array"}},{"in":"query","name":"Props","required":true,"schema":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"testParam","type":"object"}},{"in":"query","name":"SomeValue","required":true,"schema":{"example":"","format":"string","title":"SomeValue","type":"string"}}],"responses":null}}}`)
 
 	type testParam struct {
 		ParamProp string
@@ -95,7 +112,7 @@ func TestParams(t *testing.T) {
 		AnotherValue []int
 		Props        testParam
 	}
-	r, err := makeParams[param]("query")
+	r, err := makeParams[param]("query", ReadableSchemaName)
 	if err != nil {
 		t.Error(err)
 	}
@@ -106,6 +123,7 @@ func TestParams(t *testing.T) {
 		p.Ref = ""
 		prepo[k] = p
 	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Value.Name < params[j].Value.Name })
 	paths := openapi3.Paths{
 		"/query-param-test": &openapi3.PathItem{
 			Post: &openapi3.Operation{
@@ -128,7 +146,7 @@ func TestParams(t *testing.T) {
 
 func TestBuildSchemaRepo(t *testing.T) {
 
-	expectedJSON := []byte(`{"schemas":{"endpointparam":{"format":"param","properties":{"AnotherValue":{"items":{"format":"int","type":"number"},"title":"AnotherValue","type":"array"},"Props":{"format":"testParam","properties":{"ParamProp":{"format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"Props","type":"object"},"SomeValue":{"format":"string","title":"SomeValue","type":"string"}},"required":["SomeValue","AnotherValue","Props"],"type":"object"},"endpointtestParam":{"format":"testParam","properties":{"ParamProp":{"format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"Props","type":"object"}}}`)
+	expectedJSON := []byte(`{"schemas":{"param":{"example":"","format":"param","properties":{"AnotherValue":{"example":"","items":{"example":"","format":"int64","type":"integer"},"title":"AnotherValue","type":"array"},"Props":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"testParam","type":"object"},"SomeValue":{"example":"","format":"string","title":"SomeValue","type":"string"}},"required":["SomeValue","AnotherValue","Props"],"title":"param","type":"object"},"testParam":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"testParam","type":"object"}}}`)
 
 	type testParam struct {
 		ParamProp string
@@ -139,7 +157,7 @@ func TestBuildSchemaRepo(t *testing.T) {
 		Props        testParam
 	}
 	n := quick_schema.GetSchema[param]()
-	repo := buildSchemaRepo(*n)
+	repo := buildSchemaRepo(*n, ReadableSchemaName)
 	schemass := map[string]*openapi3.SchemaRef{}
 	for name, schema := range repo.Repo {
 		schemass[name] = &openapi3.SchemaRef{
@@ -164,7 +182,7 @@ func TestBuildSchemaRepo(t *testing.T) {
 
 func TestFillOpenAPIRoute(t *testing.T) {
 
-	expectedJSON := []byte(`{"components":{"schemas":{"endpointDataDetail":{"format":"DataDetail","properties":{"kind":{"format":"string","title":"kind","type":"string"},"lang":{"format":"string","title":"lang","type":"string"}},"required":["kind","lang"],"title":"DataDetail","type":"object"},"endpointDataResponse[endpoint.SingleItemData[string]]":{"format":"DataResponse[endpoint.SingleItemData[string]]","properties":{"context":{"format":"string","title":"context","type":"string"},"data":{"format":"SingleItemData[string]","properties":{"DataDetail":{"format":"DataDetail","properties":{"kind":{"format":"string","title":"kind","type":"string"},"lang":{"format":"string","title":"lang","type":"string"}},"required":["kind","lang"],"title":"DataDetail","type":"object"},"item":{"format":"string","title":"item","type":"string"}},"required":["DataDetail","item"],"title":"data","type":"object"}},"required":["context","data"],"type":"object"},"endpointSingleItemData[string]":{"format":"SingleItemData[string]","properties":{"DataDetail":{"format":"DataDetail","properties":{"kind":{"format":"string","title":"kind","type":"string"},"lang":{"format":"string","title":"lang","type":"string"}},"required":["kind","lang"],"title":"DataDetail","type":"object"},"item":{"format":"string","title":"item","type":"string"}},"required":["DataDetail","item"],"title":"data","type":"object"},"endpointbody":{"format":"body","properties":{"Content":{"format":"string","title":"Content","type":"string"}},"required":["Content"],"type":"object"}}},"info":{"title":"Endpoint Docs","version":"v1.0.1"},"openapi":"3.0","paths":{"/api/endpoint/{ParamProp}":{"get":{"parameters":[{"in":"path","name":"ParamProp","required":true,"schema":{"format":"string","title":"ParamProp","type":"string"}},{"in":"query","name":"Props","required":true,"schema":{"format":"testParam","properties":{"ParamProp":{"format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"Props","type":"object"}},{"in":"query","name":"SomeValue","required":true,"schema":{"format":"string","title":"SomeValue","type":"string"}},{"in":"query","name":"AnotherValue","required":true,"schema":{"items":{"format":"int","type":"number"},"title":"AnotherValue","type":"array"}}],"requestBody":{"content":{"application/json":{"schema":{"format":"body","properties":{"Content":{"format":"string","title":"Content","type":"string"}},"required":["Content"],"type":"object"}}},"description":"Request data"},"responses":{"200":{"content":{"application/json":{"schema":{"format":"DataResponse[endpoint.SingleItemData[string]]","properties":{"context":{"format":"string","title":"context","type":"string"},"data":{"format":"SingleItemData[string]","properties":{"DataDetail":{"format":"DataDetail","properties":{"kind":{"format":"string","title":"kind","type":"string"},"lang":{"format":"string","title":"lang","type":"string"}},"required":["kind","lang"],"title":"DataDetail","type":"object"},"item":{"format":"string","title":"item","type":"string"}},"required":["DataDetail","item"],"title":"data","type":"object"}},"required":["context","data"],"type":"object"}}}}}}}}}`)
+	expectedJSON := []byte(`{"components":{"schemas":{"DataResponseSingleItemDataString":{"example":"","properties":{"context":{"description":"Client sets this value and server echos data in the response","example":"","format":"string","nullable":true,"title":"context","type":"string"},"data":{"example":"","format":"SingleItemData[string]","properties":{"item":{"example":"","format":"string","title":"item","type":"string"},"kind":{"description":"The kind property serves as a guide to what type of information this particular object stores","example":"resource","format":"string","title":"kind","type":"string"},"lang":{"description":"Indicates the language of the rest of the properties in this object (BCP 47)","example":"pt-br","format":"string","nullable":true,"title":"lang","type":"string"}},"required":["kind","item"],"title":"SingleItemDataString","type":"object"}},"required":["data"],"title":"DataResponseSingleItemDataString","type":"object"},"SingleItemDataString":{"example":"","format":"SingleItemData[string]","properties":{"item":{"example":"","format":"string","title":"item","type":"string"},"kind":{"description":"The kind property serves as a guide to what type of information this particular object stores","example":"resource","format":"string","title":"kind","type":"string"},"lang":{"description":"Indicates the language of the rest of the properties in this object (BCP 47)","example":"pt-br","format":"string","nullable":true,"title":"lang","type":"string"}},"required":["kind","item"],"title":"SingleItemDataString","type":"object"},"body":{"example":"","format":"body","properties":{"Content":{"example":"","format":"string","title":"Content","type":"string"}},"required":["Content"],"title":"body","type":"object"},"detailError":{"example":"","format":"detailError","properties":{"domain":{"example":"","format":"string","title":"domain","type":"string"},"extendedHelp":{"example":"","format":"string","nullable":true,"type":"string"},"location":{"example":"","format":"string","nullable":true,"type":"string"},"locationType":{"example":"","format":"string","nullable":true,"type":"string"},"message":{"example":"","format":"string","title":"message","type":"string"},"reason":{"example":"","format":"string","title":"reason","type":"string"},"sendReport":{"example":"","format":"string","nullable":true,"type":"string"}},"required":["domain","reason","message"],"title":"detailError","type":"object"},"errorResponse":{"example":"","format":"errorResponse","properties":{"error":{"example":"","format":"generalError","properties":{"code":{"example":"","format":"int64","title":"code","type":"integer"},"errors":{"example":"","items":{"$ref":"#/components/schemas/detailError"},"nullable":true,"title":"errors","type":"array"},"message":{"example":"","format":"string","title":"message","type":"string"}},"required":["code","message"],"title":"generalError","type":"object"}},"required":["error"],"title":"errorResponse","type":"object"},"generalError":{"example":"","format":"generalError","properties":{"code":{"example":"","format":"int64","title":"code","type":"integer"},"errors":{"example":"","items":{"$ref":"#/components/schemas/detailError"},"nullable":true,"title":"errors","type":"array"},"message":{"example":"","format":"string","title":"message","type":"string"}},"required":["code","message"],"title":"generalError","type":"object"}}},"info":{"title":"Endpoint Docs","version":"v1.0.1"},"openapi":"3.0.0","paths":{"/api/endpoint/{ParamProp}":{"get":{"description":"description","operationId":"title","parameters":[{"in":"path","name":"ParamProp","required":true,"schema":{"example":"","format":"string","title":"ParamProp","type":"string"}},{"in":"query","name":"AnotherValue","required":true,"schema":{"example":"","items":{"example":"","format":"int64","type":"integer"},"title":"AnotherValue","type":"array"}},{"in":"query","name":"Props","required":true,"schema":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"testParam","type":"object"}},{"in":"query","name":"SomeValue","required":true,"schema":{"example":"","format":"string","title":"SomeValue","type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"example":"","format":"body","properties":{"Content":{"example":"","format":"string","title":"Content","type":"string"}},"required":["Content"],"title":"body","type":"object"}},"application/x-www-form-urlencoded":{"schema":{"example":"","format":"body","properties":{"Content":{"example":"","format":"string","title":"Content","type":"string"}},"required":["Content"],"title":"body","type":"object"}},"multipart/form-data":{"schema":{"example":"","format":"body","properties":{"Content":{"example":"","format":"string","title":"Content","type":"string"}},"required":["Content"],"title":"body","type":"object"}}},"description":"Request data"},"responses":{"200":{"content":{"application/json":{"schema":{"example":"","properties":{"context":{"description":"Client sets this value and server echos data in the response","example":"","format":"string","nullable":true,"title":"context","type":"string"},"data":{"example":"","format":"SingleItemData[string]","properties":{"item":{"example":"","format":"string","title":"item","type":"string"},"kind":{"description":"The kind property serves as a guide to what type of information this particular object stores","example":"resource","format":"string","title":"kind","type":"string"},"lang":{"description":"Indicates the language of the rest of the properties in this object (BCP 47)","example":"pt-br","format":"string","nullable":true,"title":"lang","type":"string"}},"required":["kind","item"],"title":"SingleItemDataString","type":"object"}},"required":["data"],"title":"DataResponseSingleItemDataString","type":"object"}}},"description":"endpoint success responses"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/errorResponse"}}},"description":"Bad Request"},"401":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/errorResponse"}}},"description":"Unauthorized"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/errorResponse"}}},"description":"Internal Server Error"}},"summary":"title"}}}}`)
 	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
 	type claimed struct {
 		UserID string
@@ -184,6 +202,15 @@ func TestFillOpenAPIRoute(t *testing.T) {
 		path: "/api/endpoint/{ParamProp}",
 		verb: GET,
 	}, oapi.Route("title", "description"))
+	// parameters are documented in no particular order, path parameters first
+	params := oapi.T().Paths["/api/endpoint/{ParamProp}"].Get.Parameters
+	sort.SliceStable(params, func(i, j int) bool {
+		a, b := params[i].Value, params[j].Value
+		if a.In != b.In {
+			return a.In == openapi3.ParameterInPath
+		}
+		return a.Name < b.Name
+	})
 
 	j, err := oapi.T().MarshalJSON()
 	if err != nil {
@@ -204,7 +231,7 @@ type PushTokens struct {
 
 func TestOptionalOmitempty(t *testing.T) {
 
-	expectedJSON := []byte(`{"schemas":{"github_com_pindamonhangaba_apiculi_endpoint":{"example":"","format":"param","properties":{"AnotherValue":{"example":"","items":{"example":"","format":"int","type":"number"},"title":"AnotherValue","type":"array"},"Props":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"github_com_pindamonhangaba_apiculi_endpointProps","type":"object"},"PushTokens":{"example":"","format":"StringArray","items":{"example":"","format":"string","type":"string"},"title":"github_com_lib_pqPushTokens","type":"array"},"some_value":{"example":"","format":"string","nullable":true,"title":"some_value","type":"string"}},"required":["AnotherValue","Props","PushTokens"],"title":"github_com_pindamonhangaba_apiculi_endpoint","type":"object"},"github_com_pindamonhangaba_apiculi_endpointProps":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"github_com_pindamonhangaba_apiculi_endpointProps","type":"object"}}}`)
+	expectedJSON := []byte(`{"schemas":{"param":{"example":"","format":"param","properties":{"AnotherValue":{"example":"","items":{"example":"","format":"int64","type":"integer"},"title":"AnotherValue","type":"array"},"Props":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"testParam","type":"object"},"PushTokens":{"example":"","items":{"example":"","format":"string","type":"string"},"title":"PushTokens","type":"array"},"some_value":{"example":"","format":"string","nullable":true,"title":"some_value","type":"string"}},"required":["AnotherValue","Props","PushTokens"],"title":"param","type":"object"},"testParam":{"example":"","format":"testParam","properties":{"ParamProp":{"example":"","format":"string","title":"ParamProp","type":"string"}},"required":["ParamProp"],"title":"testParam","type":"object"}}}`)
 
 	type testParam struct {
 		ParamProp string
@@ -216,7 +243,7 @@ func TestOptionalOmitempty(t *testing.T) {
 		PushTokens   PushTokens
 	}
 	n := quick_schema.GetSchema[param]()
-	repo := buildSchemaRepo(*n)
+	repo := buildSchemaRepo(*n, ReadableSchemaName)
 	schemass := map[string]*openapi3.SchemaRef{}
 	for name, schema := range repo.Repo {
 		schemass[name] = &openapi3.SchemaRef{
@@ -238,3 +265,1374 @@ func TestOptionalOmitempty(t *testing.T) {
 		t.Errorf("result not as expected:\n%v", d)
 	}
 }
+
+func TestEndpointCtx(t *testing.T) {
+	type ctxKey struct{}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	_, _, h := GorillaCtx(
+		Get("/api/ctx"),
+		oapi.Route("Context aware", "description"),
+		func(ctx context.Context, in EndpointInput[any, any, any, any]) (DataResponse[SingleItemData[string]], error) {
+			v, _ := ctx.Value(ctxKey{}).(string)
+			return DataResponse[SingleItemData[string]]{
+				Data: SingleItemData[string]{Item: v},
+			}, nil
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ctx", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "from request"))
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	var res DataResponse[SingleItemData[string]]
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Data.Item != "from request" {
+		t.Errorf("handler did not receive the request context, got %q", res.Data.Item)
+	}
+}
+
+func TestEchoWithNoResponse(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	e := echo.New()
+	e.Add(EchoWithNoResponse(
+		Post("/api/items").WithStatus(http.StatusCreated),
+		oapi.Route("Create", "description"),
+		func(in EndpointInput[any, any, any, any], c echo.Context) (DataResponse[SingleItemData[string]], error) {
+			r := DataResponse[SingleItemData[string]]{
+				Data: SingleItemData[string]{Item: "created"},
+			}
+			r.SetLocation("/api/items/1")
+			return r, nil
+		},
+	))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no body, got %s", rec.Body.String())
+	}
+	if l := rec.Header().Get("Location"); l != "/api/items/1" {
+		t.Errorf("expected the Location header, got %q", l)
+	}
+	res := oapi.T().Paths["/api/items"].Post.Responses["201"].Value
+	if len(res.Content) > 0 {
+		t.Errorf("expected the response documented without content, got %v", res.Content)
+	}
+	if res.Headers["Location"] == nil {
+		t.Errorf("expected the Location header documented")
+	}
+}
+
+func TestErrorResponsesDocs(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	type param struct {
+		ID string `json:"id"`
+	}
+	fillOpenAPIRoute[any, param, any, any, SingleItemData[string]](endpointPath{
+		path: "/api/endpoint/{id}",
+		verb: GET,
+	}, oapi.Route("title", "description", WithErrors(http.StatusNotFound)))
+
+	op := oapi.T().Paths["/api/endpoint/{id}"].Get
+	codes := []string{}
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	if strings.Join(codes, ",") != "200,400,404,500" {
+		t.Errorf("unexpected responses: %v", codes)
+	}
+	ref := op.Responses["404"].Value.Content.Get("application/json").Schema.Ref
+	if oapi.T().Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")] == nil {
+		t.Errorf("error schema %s not in components", ref)
+	}
+
+	type claims struct {
+		Sub string `json:"sub"`
+	}
+	type upload struct {
+		Avatar File `json:"avatar" file:"maxSize=1024"`
+	}
+	for name, tc := range map[string]struct {
+		path     endpointPath
+		expected string
+		fill     func(p endpointPath)
+	}{
+		"claims": {Get("/api/claims"), "200,401,500", func(p endpointPath) {
+			fillOpenAPIRoute[claims, any, any, any, SingleItemData[string]](p, oapi.Route("claims", "description"))
+		}},
+		"optional claims": {Get("/api/optional"), "200,500", func(p endpointPath) {
+			fillOpenAPIRoute[claims, any, any, any, SingleItemData[string]](p, oapi.Route("optional", "description", OptionalClaims()))
+		}},
+		"public": {Get("/api/public"), "200,500", func(p endpointPath) {
+			fillOpenAPIRoute[claims, any, any, any, SingleItemData[string]](p, oapi.Route("public", "description", Public()))
+		}},
+		"files": {Post("/api/files"), "200,400,415,422,500", func(p endpointPath) {
+			fillOpenAPIRoute[any, any, any, upload, SingleItemData[string]](p, oapi.Route("files", "description"))
+		}},
+	} {
+		tc.fill(tc.path)
+		op := oapi.T().Paths[tc.path.path].GetOperation(string(tc.path.verb))
+		codes := []string{}
+		for code := range op.Responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		if strings.Join(codes, ",") != tc.expected {
+			t.Errorf("%s: unexpected responses: %v", name, codes)
+		}
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	type query struct {
+		ID      int64     `json:"id"`
+		Tags    []string  `json:"tags"`
+		Active  bool      `json:"active"`
+		Ratio   *float64  `json:"ratio"`
+		Since   time.Time `json:"since"`
+		OwnerID uuid.UUID `json:"ownerID"`
+		Legacy  int64     `json:"legacy,string"`
+	}
+	q, err := decodeValues[query](paramValues{values: map[string][]string{
+		"id":      {"42"},
+		"tags":    {"one"},
+		"active":  {"true"},
+		"ratio":   {"0.5"},
+		"since":   {"2023-01-02T15:04:05Z"},
+		"ownerID": {"0b5d2e4c-6ad4-4b8a-9d16-3a0f0e3d1a2b"},
+		"legacy":  {"7"},
+	}}, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.ID != 42 || len(q.Tags) != 1 || q.Tags[0] != "one" || !q.Active || *q.Ratio != 0.5 ||
+		q.Since.Year() != 2023 || q.OwnerID.String() != "0b5d2e4c-6ad4-4b8a-9d16-3a0f0e3d1a2b" || q.Legacy != 7 {
+		t.Errorf("unexpected decoded values: %+v", q)
+	}
+
+	_, err = decodeValues[query](paramValues{values: map[string][]string{
+		"id":     {"forty-two"},
+		"active": {"maybe"},
+	}}, "query")
+	e := AsError(err)
+	if e.Code != http.StatusBadRequest || len(e.Details) != 2 || e.Details[0].Location != "id" || e.Details[1].Location != "active" {
+		t.Errorf("unexpected error: %+v", e)
+	}
+}
+
+type invoice struct {
+	ID string `json:"id"`
+}
+
+func (invoice) SchemaName() string { return "Document" }
+
+func (invoice) data() {}
+
+type receipt struct {
+	ID int `json:"id"`
+}
+
+func (receipt) SchemaName() string { return "Document" }
+
+func (receipt) data() {}
+
+type category struct {
+	Name     string      `json:"name"`
+	Parent   *category   `json:"parent,omitempty"`
+	Children []*category `json:"children"`
+}
+
+type objTree map[string]objTree
+
+type listTree []listTree
+
+func (category) data() {}
+
+func TestMapSchemas(t *testing.T) {
+	type label string
+	type body struct {
+		Metadata map[string]any            `json:"metadata"`
+		Labels   map[label]string          `json:"labels"`
+		Scores   map[int]float64           `json:"scores"`
+		Counts   map[uint8]int             `json:"counts"`
+		Items    map[string]*category      `json:"items"`
+		Nested   map[string]map[string]int `json:"nested"`
+	}
+	s := buildSchemaRepo(*quick_schema.GetSchema[body](), ReadableSchemaName).Start
+
+	for name, p := range s.Properties {
+		if p.Value.Type != "object" {
+			t.Errorf("expected %s to be an object, got %s", name, p.Value.Type)
+		}
+	}
+	if s.Properties["metadata"].Value.AdditionalProperties.Schema != nil {
+		t.Error("expected metadata to allow any value")
+	}
+	if v := s.Properties["labels"].Value.AdditionalProperties.Schema; v == nil || v.Value.Type != "string" {
+		t.Errorf("expected string labels, got %+v", v)
+	}
+	expected := map[string]string{"labels": "", "scores": "^-?[0-9]+$", "counts": "^[0-9]+$"}
+	for name, pattern := range expected {
+		names, _ := s.Properties[name].Value.Extensions["x-propertyNames"].(map[string]interface{})
+		if got, _ := names["pattern"].(string); got != pattern {
+			t.Errorf("expected %s keys pattern %q, got %q", name, pattern, got)
+		}
+	}
+	if v := s.Properties["items"].Value.AdditionalProperties.Schema; v == nil || v.Ref != "#/components/schemas/category" {
+		t.Errorf("expected items to reference category, got %+v", v)
+	}
+	v := s.Properties["nested"].Value.AdditionalProperties.Schema
+	if v == nil || v.Value.Type != "object" || v.Value.AdditionalProperties.Schema.Value.Type != "integer" {
+		t.Errorf("expected nested maps of integers, got %+v", v)
+	}
+}
+
+type status string
+
+func (status) Enum() []any { return []any{status("active"), status("blocked")} }
+
+type priority int
+
+func (priority) Enum() []any { return []any{priority(1), priority(2), priority(3)} }
+
+type event interface {
+	event()
+}
+
+type photoEvent struct {
+	DataDetail
+	URL string `json:"url"`
+}
+
+func (photoEvent) event() {}
+
+type noteEvent struct {
+	DataDetail
+	Text string `json:"text" validate:"minLength=1"`
+}
+
+func (*noteEvent) event() {}
+
+func TestOpenAPI31(t *testing.T) {
+	type order struct {
+		ID     int     `json:"id" example:"7"`
+		Note   *string `json:"note"`
+		Parent *order  `json:"parent"`
+	}
+	oapi := NewOpenAPI("Test", "v1")
+	fillOpenAPIRoute[any, any, any, order, SingleItemData[string]](Post("/api/order"), oapi.Route("Create order", ""))
+	Webhook[order]("orderCreated", oapi.Route("Order created", "Sent once an order is paid"))
+
+	decode := func() map[string]any {
+		b, err := oapi.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := map[string]any{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	doc := decode()
+	if doc["openapi"] != "3.0.0" || doc["x-webhooks"] == nil || doc["webhooks"] != nil {
+		t.Errorf("expected a 3.0 document with x-webhooks, got %v %v", doc["openapi"], doc["webhooks"])
+	}
+
+	oapi.SetVersion(OpenAPI31)
+	doc = decode()
+	if doc["openapi"] != "3.1.0" || doc["x-webhooks"] != nil {
+		t.Errorf("expected a 3.1 document, got %v", doc["openapi"])
+	}
+	if b, _ := json.Marshal(oapi); !strings.Contains(string(b), `"openapi":"3.1.0"`) {
+		t.Errorf("json.Marshal didn't encode a 3.1 document: %.100s", b)
+	}
+	if oapi.T().OpenAPI != OpenAPI30 {
+		t.Errorf("T isn't a 3.0 document: %s", oapi.T().OpenAPI)
+	}
+	webhook, _ := doc["webhooks"].(map[string]any)["orderCreated"].(map[string]any)
+	if webhook["post"] == nil {
+		t.Errorf("webhook not documented: %v", doc["webhooks"])
+	}
+	schema := doc["components"].(map[string]any)["schemas"].(map[string]any)["order"].(map[string]any)
+	props := schema["properties"].(map[string]any)
+	if b, _ := json.Marshal(props["note"]); !strings.Contains(string(b), `"type":["string","null"]`) || strings.Contains(string(b), "nullable") {
+		t.Errorf("expected a nullable note, got %s", b)
+	}
+	if b, _ := json.Marshal(props["id"]); !strings.Contains(string(b), `"examples":["7"]`) || strings.Contains(string(b), `"example"`) {
+		t.Errorf("expected examples of id, got %s", b)
+	}
+	if b, _ := json.Marshal(props["parent"]); !strings.Contains(string(b), `"$ref":"#/components/schemas/order"`) {
+		t.Errorf("expected a reference to order, got %s", b)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("unsupported version accepted")
+		}
+	}()
+	oapi.SetVersion("2.0")
+}
+
+type animal interface {
+	sound() string
+}
+
+type dog struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+func (dog) sound() string { return "woof" }
+
+type Adopter struct {
+	AdopterName string `json:"adopter"`
+}
+
+type adoption struct {
+	*Adopter
+	Pet animal `json:"pet"`
+}
+
+// petName decodes a dog from its name
+type petName struct {
+	Pet animal
+}
+
+func (p *petName) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	p.Pet = dog{Kind: "dog", Name: name}
+	return nil
+}
+
+func TestUnmarshalOneOf(t *testing.T) {
+	var a adoption
+	if err := unmarshalJSON([]byte(`{"pet":{"kind":"dog","name":"rex"}}`), &a); err == nil {
+		t.Errorf("unregistered interface decoded: %+v", a)
+	}
+
+	// registered after the type was first decoded
+	quick_schema.RegisterOneOf[animal]("kind", map[string]animal{"dog": dog{}})
+	a = adoption{}
+	if err := unmarshalJSON([]byte(`{"pet":{"kind":"dog","name":"rex"}}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := a.Pet.(dog); !ok || d.Name != "rex" {
+		t.Errorf("expected a dog, got %#v", a.Pet)
+	}
+	if a.Adopter != nil {
+		t.Errorf("embedded pointer allocated without its fields: %+v", a.Adopter)
+	}
+	if err := unmarshalJSON([]byte(`{"adopter":"ana","pet":{"kind":"dog","name":"rex"}}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Adopter == nil || a.AdopterName != "ana" {
+		t.Errorf("expected the adopter, got %+v", a.Adopter)
+	}
+
+	var p petName
+	if err := unmarshalJSON([]byte(`"rex"`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := p.Pet.(dog); !ok || d.Name != "rex" {
+		t.Errorf("UnmarshalJSON not used, got %#v", p.Pet)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	expectedJSON := []byte(`{"error":{"code":404,"message":"resource not found","errors":[{"domain":"resource","reason":"notFound","message":"resource 42 does not exist","location":"id","locationType":"path"}]}}`)
+
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	handler := func(in EndpointInput[any, struct {
+		ID string `json:"id"`
+	}, any, any]) (DataResponse[SingleItemData[string]], error) {
+		return DataResponse[SingleItemData[string]]{}, NewError(
+			http.StatusNotFound,
+			"resource not found",
+			Detail("resource", "notFound", "resource "+in.Params.ID+" does not exist").At("id", "path"),
+		)
+	}
+
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(Get("/api/gorilla/{id}"), oapi.Route("gorilla", "description"), handler)
+	router.HandleFunc(pattern, h).Methods(method)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/gorilla/42", nil))
+
+	app := fiber.New(fiber.Config{ErrorHandler: FiberErrorHandler})
+	app.Add(Fiber(Get("/api/fiber/:id"), oapi.Route("fiber", "description"), handler))
+	fres, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/fiber/42", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbody, err := io.ReadAll(fres.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, res := range map[string]struct {
+		code int
+		body []byte
+	}{
+		"gorilla": {rec.Code, rec.Body.Bytes()},
+		"fiber":   {fres.StatusCode, fbody},
+	} {
+		if res.code != http.StatusNotFound {
+			t.Errorf("%s: unexpected status %d", name, res.code)
+		}
+		d, err := diffJSON(expectedJSON, res.body)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(d) > 0 {
+			t.Errorf("%s: result not as expected:\n%v", name, d)
+		}
+	}
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	expectedJSON := []byte(`{"error":{"code":500,"message":"Internal Server Error"}}`)
+	internal := errors.New("connecting to db: password authentication failed")
+
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	handler := func(in EndpointInput[any, any, any, any]) (DataResponse[SingleItemData[string]], error) {
+		return DataResponse[SingleItemData[string]]{}, internal
+	}
+
+	var echoErr, fiberErr error
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			echoErr = next(c)
+			return echoErr
+		}
+	})
+	e.Add(Echo(Get("/api/echo"), oapi.Route("echo", "description"), handler))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/echo", nil))
+
+	app := fiber.New(fiber.Config{ErrorHandler: FiberErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		fiberErr = c.Next()
+		return fiberErr
+	})
+	app.Add(Fiber(Get("/api/fiber"), oapi.Route("fiber", "description"), handler))
+	fres, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/fiber", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbody, err := io.ReadAll(fres.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, res := range map[string]struct {
+		code int
+		body []byte
+		err  error
+	}{
+		"echo":  {rec.Code, rec.Body.Bytes(), echoErr},
+		"fiber": {fres.StatusCode, fbody, fiberErr},
+	} {
+		if res.code != http.StatusInternalServerError {
+			t.Errorf("%s: unexpected status %d", name, res.code)
+		}
+		d, err := diffJSON(expectedJSON, res.body)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(d) > 0 {
+			t.Errorf("%s: result not as expected:\n%v", name, d)
+		}
+		if !errors.Is(res.err, internal) {
+			t.Errorf("%s: middleware got %v, expected the handler's error", name, res.err)
+		}
+	}
+}
+
+func TestSuccessStatus(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+
+	method, pattern, h := Gorilla(
+		Post("/api/resource").WithStatus(http.StatusCreated),
+		oapi.Route("Create resource", "description"),
+		func(in EndpointInput[any, any, any, struct {
+			Name string `json:"name"`
+		}]) (res DataResponse[SingleItemData[string]], err error) {
+			res.Data.Item = in.Body.Name
+			res.SetLocation("/api/resource/" + in.Body.Name)
+			return res, nil
+		},
+	)
+	router.HandleFunc(pattern, h).Methods(method)
+	method, pattern, h = Gorilla(
+		Delete("/api/resource/{id}").WithStatus(http.StatusNoContent),
+		oapi.Route("Delete resource", "description"),
+		func(in EndpointInput[any, struct {
+			ID string `json:"id"`
+		}, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+			return res, nil
+		},
+	)
+	router.HandleFunc(pattern, h).Methods(method)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/resource", strings.NewReader(`{"name":"first"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/api/resource/first" {
+		t.Errorf("unexpected created response %d %v", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/resource/first", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Body.Len() > 0 {
+		t.Errorf("unexpected no content response %d %s", rec.Code, rec.Body.String())
+	}
+
+	created := oapi.T().Paths["/api/resource"].Post.Responses["201"]
+	if created == nil || created.Value.Headers["Location"] == nil {
+		t.Errorf("201 response not documented with Location header")
+	}
+	deleted := oapi.T().Paths["/api/resource/{id}"].Delete.Responses["204"]
+	if deleted == nil || deleted.Value.Content != nil {
+		t.Errorf("204 response not documented without content")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	type body struct {
+		Name  string   `json:"name" validate:"required,minLength=3" pattern:"^[a-z]+$"`
+		Email string   `json:"email" validate:"format=email"`
+		Age   int      `json:"age" validate:"min=18,max=150"`
+		Kind  string   `json:"kind,omitempty" validate:"enum=person|company"`
+		Tags  []string `json:"tags" validate:"maxItems=2"`
+		// 0 and false are values, not missing ones
+		Count  int  `json:"count" validate:"required"`
+		Active bool `json:"active" validate:"required"`
+	}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(
+		Post("/api/person"),
+		oapi.Route("Create person", "description"),
+		func(in EndpointInput[any, any, any, body]) (res DataResponse[SingleItemData[string]], err error) {
+			res.Data.Item = in.Body.Name
+			return res, nil
+		},
+	)
+	router.HandleFunc(pattern, h).Methods(method)
+
+	post := func(b string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/person", strings.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"name":"joe","email":"joe@example.com","age":30,"tags":["a"],"count":0,"active":false}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("valid body rejected %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = post(`{"name":"Jo","email":"joe","age":10,"kind":"robot","tags":["a","b","c"]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid body accepted %d: %s", rec.Code, rec.Body.String())
+	}
+	var res errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	locations := []string{}
+	for _, e := range res.Error.Errors {
+		if e.LocationType == nil || *e.LocationType != "body" || e.Location == nil {
+			t.Errorf("missing location in %+v", e)
+			continue
+		}
+		locations = append(locations, *e.Location)
+	}
+	if strings.Join(locations, ",") != "name,email,age,kind,tags" {
+		t.Errorf("unexpected failing fields: %v", locations)
+	}
+
+	schema := oapi.T().Paths["/api/person"].Post.RequestBody.Value.Content.Get("application/json").Schema.Value
+	if schema.Properties["name"].Value.MinLength != 3 || schema.Properties["name"].Value.Pattern != "^[a-z]+$" ||
+		schema.Properties["email"].Value.Format != "email" || *schema.Properties["age"].Value.Min != 18 ||
+		len(schema.Properties["kind"].Value.Enum) != 2 || *schema.Properties["tags"].Value.MaxItems != 2 {
+		t.Errorf("constraints not documented: %+v", schema.Properties)
+	}
+	if oapi.T().Paths["/api/person"].Post.Responses["422"] == nil {
+		t.Errorf("validation error response not documented")
+	}
+}
+
+func TestHeaderCookieParams(t *testing.T) {
+	type query struct {
+		Context   string `json:"context"`
+		IfMatch   string `json:"If-Match" in:"header"`
+		RequestID int64  `json:"X-Request-ID,omitempty" in:"header"`
+		Session   string `json:"session" in:"cookie" validate:"required"`
+	}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(
+		Get("/api/headers"),
+		oapi.Route("Headers", "description"),
+		func(in EndpointInput[any, any, query, any]) (res DataResponse[SingleItemData[string]], err error) {
+			res.Context = in.Query.Context
+			res.Data.Item = fmt.Sprintf("%s %d %s", in.Query.IfMatch, in.Query.RequestID, in.Query.Session)
+			return res, nil
+		},
+	)
+	router.HandleFunc(pattern, h).Methods(method)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/headers?context=c", nil)
+	req.Header.Set("If-Match", `"etag"`)
+	req.Header.Set("X-Request-ID", "12")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var res DataResponse[SingleItemData[string]]
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Data.Item != `"etag" 12 abc` {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/headers", nil))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"locationType":"cookie"`) {
+		t.Errorf("missing cookie accepted %d: %s", rec.Code, rec.Body.String())
+	}
+
+	ins := map[string]string{}
+	for _, p := range oapi.T().Paths["/api/headers"].Get.Parameters {
+		ins[p.Value.Name] = p.Value.In
+	}
+	if ins["context"] != "query" || ins["If-Match"] != "header" || ins["X-Request-ID"] != "header" || ins["session"] != "cookie" {
+		t.Errorf("unexpected documented parameters: %v", ins)
+	}
+}
+
+func TestFileUpload(t *testing.T) {
+	type upload struct {
+		Title  string `json:"title" validate:"required"`
+		Avatar File   `json:"avatar" file:"maxSize=16,accept=image/*"`
+		Docs   []File `json:"docs,omitempty"`
+	}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(
+		Post("/api/upload"),
+		oapi.Route("Upload", "description"),
+		func(in EndpointInput[any, any, any, upload]) (res DataResponse[SingleItemData[string]], err error) {
+			f, err := in.Body.Avatar.Open()
+			if err != nil {
+				return res, err
+			}
+			defer f.Close()
+			b, err := io.ReadAll(f)
+			res.Data.Item = fmt.Sprintf("%s %s %s %d", in.Body.Title, in.Body.Avatar.Filename, b, len(in.Body.Docs))
+			return res, err
+		},
+	)
+	router.HandleFunc(pattern, h).Methods(method)
+
+	send := func(contentType, content string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("title", "hello")
+		hdr := textproto.MIMEHeader{}
+		hdr.Set("Content-Disposition", `form-data; name="avatar"; filename="a.png"`)
+		hdr.Set("Content-Type", contentType)
+		fw, _ := mw.CreatePart(hdr)
+		fw.Write([]byte(content))
+		for _, n := range []string{"a.pdf", "b.pdf"} {
+			fw, _ := mw.CreateFormFile("docs", n)
+			fw.Write([]byte(n))
+		}
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/upload", body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("image/png", "png")
+	var res DataResponse[SingleItemData[string]]
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Data.Item != "hello a.png png 2" {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = send("text/plain", "png")
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"reason":"invalidFile"`) {
+		t.Errorf("wrong content-type accepted %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send("image/png", "a file larger than sixteen bytes")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("large file accepted %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send("image/png", strings.Repeat("a", defaultMaxBodySize))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body larger than the route's limit accepted %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// File fields can't be filled from other bodies
+	for _, contentType := range []string{"application/json", "application/x-www-form-urlencoded"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(`{"title":"hello","avatar":{"Filename":"a.png","Size":3}}`))
+		req.Header.Set("Content-Type", contentType)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s body accepted %d: %s", contentType, rec.Code, rec.Body.String())
+		}
+	}
+
+	content := oapi.T().Paths["/api/upload"].Post.RequestBody.Value.Content
+	if len(content) != 1 || content["multipart/form-data"] == nil {
+		t.Fatalf("unexpected request content types: %v", content)
+	}
+	mt := content["multipart/form-data"]
+	avatar := mt.Schema.Value.Properties["avatar"].Value
+	if avatar.Type != "string" || avatar.Format != "binary" {
+		t.Errorf("unexpected avatar schema %s/%s", avatar.Type, avatar.Format)
+	}
+	if mt.Encoding["avatar"] == nil || mt.Encoding["avatar"].ContentType != "image/*" {
+		t.Errorf("missing avatar encoding: %v", mt.Encoding)
+	}
+}
+
+func TestClaimsProvider(t *testing.T) {
+	type claims struct {
+		Name string `json:"name"`
+	}
+	handler := func(in EndpointInput[claims, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+		res.Data.Item = in.Claims.Name
+		return res, nil
+	}
+	serve := func(h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	_, _, h := Gorilla(Get("/api/jwt"), oapi.Route("JWT", "description"), handler)
+	req := httptest.NewRequest(http.MethodGet, "/api/jwt", nil)
+	token := &jwt.Token{Claims: jwt.MapClaims{"name": "from jwt"}}
+	rec := serve(h, req.WithContext(context.WithValue(req.Context(), "user", token)))
+	if !strings.Contains(rec.Body.String(), `"item":"from jwt"`) {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/jwt", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing claims accepted %d: %s", rec.Code, rec.Body.String())
+	}
+
+	_, _, h = Gorilla(Get("/api/optional"), oapi.Route("Optional", "description", OptionalClaims()), handler)
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/optional", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("optional claims rejected %d: %s", rec.Code, rec.Body.String())
+	}
+
+	oapi.SetClaimsProvider(ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		if key := src.Header("X-API-Key"); len(key) > 0 {
+			return claims{Name: "key " + key}, nil
+		}
+		return nil, nil
+	}))
+	// routes registered before the provider was set use it too
+	req = httptest.NewRequest(http.MethodGet, "/api/optional", nil)
+	req.Header.Set("X-API-Key", "def")
+	rec = serve(h, req)
+	if !strings.Contains(rec.Body.String(), `"item":"key def"`) {
+		t.Errorf("provider set after the route was registered not used %d: %s", rec.Code, rec.Body.String())
+	}
+	_, _, h = Gorilla(Get("/api/key"), oapi.Route("Key", "description"), handler)
+	req = httptest.NewRequest(http.MethodGet, "/api/key", nil)
+	req.Header.Set("X-API-Key", "abc")
+	rec = serve(h, req)
+	if !strings.Contains(rec.Body.String(), `"item":"key abc"`) {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	_, _, h = Gorilla(Get("/api/banned"), oapi.Route("Banned", "description", WithClaimsProvider(ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		return nil, errors.Wrap(NewError(http.StatusForbidden, "banned key"), "checking key")
+	}))), handler)
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/banned", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("wrapped provider error answered %d: %s", rec.Code, rec.Body.String())
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: FiberErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("session", map[string]string{"name": "from session"})
+		return c.Next()
+	})
+	app.Add(Fiber(Get("/api/session"), oapi.Route("Session", "description", WithClaimsProvider(ContextClaims("session"))), handler))
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/session", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(b), `"item":"from session"`) {
+		t.Errorf("unexpected response %d: %s", resp.StatusCode, b)
+	}
+}
+
+func TestSecurity(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	oapi.AddJWTBearerAuth("jwt")
+	admin := oapi.RouteGroup("admin")
+	admin = admin.With(WithSecurity("jwt", "admin"))
+	handler := func(in EndpointInput[any, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+		return res, nil
+	}
+	router := mux.NewRouter()
+	for path, d := range map[string]OpenAPIRouteDescriber{
+		"/api/admin":  admin.Route("Admin", "description"),
+		"/api/reader": admin.Route("Reader", "description", WithSecurity("jwt", "read"), WithSecurity("jwt", "admin")),
+		"/api/status": admin.Route("Status", "description", Public()),
+	} {
+		method, pattern, h := Gorilla(Get(path), d, handler)
+		router.HandleFunc(pattern, h).Methods(method)
+	}
+
+	serve := func(path string, scope string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if len(scope) > 0 {
+			token := &jwt.Token{Claims: jwt.MapClaims{"scope": scope}}
+			req = req.WithContext(context.WithValue(req.Context(), "user", token))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, c := range []struct {
+		path, scope string
+		code        int
+	}{
+		{"/api/admin", "", http.StatusUnauthorized},
+		{"/api/admin", "read", http.StatusForbidden},
+		{"/api/admin", "read admin", http.StatusOK},
+		{"/api/reader", "read", http.StatusOK},
+		{"/api/reader", "write", http.StatusForbidden},
+		{"/api/status", "", http.StatusOK},
+	} {
+		rec := serve(c.path, c.scope)
+		if rec.Code != c.code {
+			t.Errorf("%s with scope %q: expected %d, got %d: %s", c.path, c.scope, c.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	paths := oapi.T().Paths
+	adminOp, readerOp, statusOp := paths["/api/admin"].Get, paths["/api/reader"].Get, paths["/api/status"].Get
+	if j, _ := json.Marshal(adminOp.Security); string(j) != `[{"jwt":["admin"]}]` {
+		t.Errorf("unexpected admin security %s", j)
+	}
+	if j, _ := json.Marshal(readerOp.Security); string(j) != `[{"jwt":["read"]},{"jwt":["admin"]}]` {
+		t.Errorf("unexpected reader security %s", j)
+	}
+	if statusOp.Security == nil || len(*statusOp.Security) != 0 {
+		t.Errorf("status route isn't public: %v", statusOp.Security)
+	}
+	if adminOp.Responses["401"] == nil || adminOp.Responses["403"] == nil || statusOp.Responses["401"] != nil {
+		t.Errorf("unexpected documented errors")
+	}
+}
+
+func TestSecuritySchemes(t *testing.T) {
+	type claims struct {
+		Client string   `json:"client"`
+		Scope  []string `json:"scopes"`
+	}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	oapi.AddAPIKeyAuth("partnerKey", "header", "X-API-Key", func(key string) (any, error) {
+		switch key {
+		case "secret":
+			return claims{Client: "partner"}, nil
+		case "reader":
+			return claims{Client: "reader", Scope: []string{"reports:read"}}, nil
+		}
+		return nil, NewError(http.StatusUnauthorized, "unknown api key")
+	})
+	oapi.AddBasicAuth("basic", func(username, password string) (any, error) {
+		if password != "pass" {
+			return nil, nil
+		}
+		return claims{Client: username}, nil
+	})
+	oapi.AddOAuth2Auth("oauth", func(token string) (any, error) {
+		return claims{Client: "service", Scope: strings.Split(token, ",")}, nil
+	}, ClientCredentialsFlow("https://auth.example.com/token", map[string]string{"reports:read": "read reports"}))
+	oapi.AddOpenIDConnectAuth("oidc", "https://auth.example.com/.well-known/openid-configuration", nil)
+	// only read by routes without security, or secured by schemes without their own provider
+	oapi.SetClaimsProvider(JWTClaims("user"))
+
+	router := mux.NewRouter()
+	for path, d := range map[string]OpenAPIRouteDescriber{
+		"/api/partner": oapi.Route("Partner", "description", WithSecurity("partnerKey"), WithSecurity("basic")),
+		"/api/reports": oapi.Route("Reports", "description", WithSecurity("oauth", "reports:read")),
+		"/api/key":     oapi.Route("Key", "description", WithSecurity("partnerKey")),
+		"/api/mixed":   oapi.Route("Mixed", "description", WithSecurity("partnerKey", "admin"), WithSecurity("oauth", "reports:read")),
+	} {
+		method, pattern, h := Gorilla(Get(path), d, func(in EndpointInput[claims, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+			res.Data.Item = in.Claims.Client
+			return res, nil
+		})
+		router.HandleFunc(pattern, h).Methods(method)
+	}
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header = header
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	basic := httptest.NewRequest(http.MethodGet, "/", nil)
+	basic.SetBasicAuth("admin", "pass")
+	for _, c := range []struct {
+		path   string
+		header http.Header
+		code   int
+		item   string
+	}{
+		{"/api/partner", http.Header{"X-Api-Key": {"secret"}}, http.StatusOK, "partner"},
+		{"/api/partner", http.Header{"X-Api-Key": {"wrong"}}, http.StatusUnauthorized, ""},
+		{"/api/partner", basic.Header, http.StatusOK, "admin"},
+		{"/api/partner", http.Header{}, http.StatusUnauthorized, ""},
+		{"/api/reports", http.Header{"Authorization": {"Bearer reports:read"}}, http.StatusOK, "service"},
+		{"/api/reports", http.Header{"Authorization": {"Bearer other"}}, http.StatusForbidden, ""},
+		// the key's scopes only meet the key's requirement
+		{"/api/mixed", http.Header{"X-Api-Key": {"reader"}}, http.StatusForbidden, ""},
+		{"/api/mixed", http.Header{"Authorization": {"Bearer reports:read"}}, http.StatusOK, "service"},
+	} {
+		rec := serve(c.path, c.header)
+		if rec.Code != c.code || !strings.Contains(rec.Body.String(), c.item) {
+			t.Errorf("%s with %v: expected %d %s, got %d: %s", c.path, c.header, c.code, c.item, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/key", nil)
+	token := &jwt.Token{Claims: jwt.MapClaims{"client": "from jwt"}}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), "user", token)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("api key route accepted a JWT %d: %s", rec.Code, rec.Body.String())
+	}
+
+	j, err := json.Marshal(oapi.T().Components.SecuritySchemes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"basic":{"scheme":"basic","type":"http"},"oauth":{"flows":{"clientCredentials":{"scopes":{"reports:read":"read reports"},"tokenUrl":"https://auth.example.com/token"}},"type":"oauth2"},"oidc":{"openIdConnectUrl":"https://auth.example.com/.well-known/openid-configuration","type":"openIdConnect"},"partnerKey":{"in":"header","name":"X-API-Key","type":"apiKey"}}`
+	if string(j) != expected {
+		t.Errorf("unexpected security schemes %s", j)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("undeclared scope accepted")
+		}
+	}()
+	Gorilla(Get("/api/undeclared"), oapi.Route("Undeclared", "description", WithSecurity("oauth", "reports:write")), func(in EndpointInput[claims, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+		return res, nil
+	})
+}
+
+func TestCall(t *testing.T) {
+	type params struct {
+		ID   int64     `json:"id"`
+		Slug string    `json:"slug"`
+		At   time.Time `json:"at,omitempty" in:"header"`
+	}
+	type query struct {
+		Context string   `json:"context"`
+		Tags    []string `json:"tags,omitempty"`
+		Page    *int     `json:"page,omitempty"`
+	}
+	type body struct {
+		Name string `json:"name" validate:"required"`
+	}
+	type item struct {
+		ID   int64    `json:"id"`
+		Slug string   `json:"slug"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+		At   string   `json:"at"`
+	}
+	type input = EndpointInput[any, params, query, body]
+	route := Put("/api/collection/{id}/{slug}").WithStatus(http.StatusCreated)
+
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(route, oapi.Route("Call", "description"),
+		func(in input) (res DataResponse[SingleItemData[item]], err error) {
+			res.Context = in.Query.Context
+			res.Data.Item = item{ID: in.Params.ID, Slug: in.Params.Slug, Name: in.Body.Name, Tags: in.Query.Tags, At: in.Params.At.Format(time.DateOnly)}
+			res.SetLocation(fmt.Sprintf("/api/collection/%d", in.Params.ID))
+			return res, nil
+		},
+	)
+	router.HandleFunc(pattern, h).Methods(method)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	client := Client{BaseURL: srv.URL}
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	res, err := Call[any, params, query, body, SingleItemData[item]](context.Background(), client, route, input{
+		Params: params{ID: 3, Slug: "a b", At: at},
+		Query:  query{Context: "ctx", Tags: []string{"x", "y"}},
+		Body:   body{Name: "name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := item{ID: 3, Slug: "a b", Name: "name", Tags: []string{"x", "y"}, At: "2024-03-01"}
+	if res.Context != "ctx" || fmt.Sprint(res.Data.Item) != fmt.Sprint(expected) || res.Location() != "/api/collection/3" {
+		t.Errorf("unexpected response %+v %s", res, res.Location())
+	}
+
+	_, err = Call[any, params, query, body, SingleItemData[item]](context.Background(), client, route, input{
+		Params: params{ID: 3, Slug: "a"},
+	})
+	var e *Error
+	if !errors.As(err, &e) || e.Code != http.StatusUnprocessableEntity || len(e.Details) != 1 || e.Details[0].Location != "name" {
+		t.Errorf("unexpected error %#v", err)
+	}
+}
+
+func TestCallWithoutBody(t *testing.T) {
+	type params struct {
+		ID int64 `json:"id"`
+	}
+	type input = EndpointInput[any, params, any, any]
+	route := Delete("/api/collection/{id}").WithStatus(http.StatusNoContent)
+	deleted := []int64{}
+	handler := func(in input) (res DataResponse[SingleItemData[string]], err error) {
+		deleted = append(deleted, in.Params.ID)
+		return res, nil
+	}
+
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(route, oapi.Route("Delete", "description"), handler)
+	router.HandleFunc(pattern, h).Methods(method)
+	gorilla := httptest.NewServer(router)
+	defer gorilla.Close()
+
+	e := echo.New()
+	e.Add(Echo(Delete("/api/collection/:id").WithStatus(http.StatusNoContent), oapi.Route("Echo delete", "description"), handler))
+	echoSrv := httptest.NewServer(e)
+	defer echoSrv.Close()
+
+	app := fiber.New(fiber.Config{ErrorHandler: FiberErrorHandler, DisableStartupMessage: true})
+	app.Add(Fiber(Delete("/api/collection/:id").WithStatus(http.StatusNoContent), oapi.Route("Fiber delete", "description"), handler))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	for name, url := range map[string]string{
+		"gorilla": gorilla.URL,
+		"echo":    echoSrv.URL,
+		"fiber":   "http://" + ln.Addr().String(),
+	} {
+		deleted = deleted[:0]
+		_, err := Call[any, params, any, any, SingleItemData[string]](context.Background(), Client{BaseURL: url}, route, input{
+			Params: params{ID: 7},
+		})
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if len(deleted) != 1 || deleted[0] != 7 {
+			t.Errorf("%s: handler not called, deleted %v", name, deleted)
+		}
+	}
+}
+
+func TestSchemaNames(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	oapi.SetSchemaNamer(QualifiedSchemaName)
+	Gorilla(Get("/api/item"), oapi.Route("Item", "description"), func(in EndpointInput[any, any, any, any]) (res DataResponse[SingleItemData[string]], err error) {
+		return res, nil
+	})
+	Gorilla(Get("/api/invoice"), oapi.Route("Invoice", "description"), func(in EndpointInput[any, any, any, any]) (res DataResponse[invoice], err error) {
+		return res, nil
+	})
+	schemas := oapi.T().Components.Schemas
+	for _, name := range []string{"EndpointDataResponseSingleItemDataString", "EndpointSingleItemDataString", "EndpointDocument", "EndpointDataResponseInvoice"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("missing schema %s", name)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("schema name collision accepted")
+		}
+	}()
+	Gorilla(Get("/api/receipt"), oapi.Route("Receipt", "description"), func(in EndpointInput[any, any, any, any]) (res DataResponse[receipt], err error) {
+		return res, nil
+	})
+}
+
+func TestLocalSchemaNames(t *testing.T) {
+	panics := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: schema name collision accepted", name)
+			}
+		}()
+		f()
+	}
+
+	// function-local types with the same name only differ by their reflect.Type
+	type line struct {
+		SKU string `json:"sku"`
+	}
+	type firstLine = line
+	{
+		type line struct {
+			Price float64 `json:"price"`
+		}
+		panics("same body", func() {
+			oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+			Gorilla(Post("/api/lines"), oapi.Route("Lines", "description"), func(in EndpointInput[any, any, any, struct {
+				First  firstLine `json:"first"`
+				Second line      `json:"second"`
+			}]) (res DataResponse[SingleItemData[string]], err error) {
+				return res, nil
+			})
+		})
+		panics("two routes", func() {
+			oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+			Gorilla(Post("/api/first"), oapi.Route("First", "description"), func(in EndpointInput[any, any, any, firstLine]) (res DataResponse[SingleItemData[string]], err error) {
+				return res, nil
+			})
+			Gorilla(Post("/api/second"), oapi.Route("Second", "description"), func(in EndpointInput[any, any, any, line]) (res DataResponse[SingleItemData[string]], err error) {
+				return res, nil
+			})
+		})
+	}
+}
+
+func TestRecursiveSchemas(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	Gorilla(Post("/api/category"), oapi.Route("category.Create", "description"), func(in EndpointInput[any, any, any, category]) (res DataResponse[category], err error) {
+		return res, nil
+	})
+
+	swag := oapi.T()
+	s, ok := swag.Components.Schemas["category"]
+	if !ok {
+		t.Fatal("missing category schema")
+	}
+	if ref := s.Value.Properties["parent"].Ref; ref != "#/components/schemas/category" {
+		t.Errorf("expected parent to reference category, got %q", ref)
+	}
+	if ref := s.Value.Properties["children"].Value.Items.Ref; ref != "#/components/schemas/category" {
+		t.Errorf("expected children to reference category, got %q", ref)
+	}
+	body := swag.Paths["/api/category"].Post.RequestBody.Value.Content["application/json"].Schema.Value
+	if ref := body.Properties["parent"].Ref; ref != "#/components/schemas/category" {
+		t.Errorf("expected body parent to reference category, got %q", ref)
+	}
+
+	// named maps and slices nested in themselves
+	Gorilla(Post("/api/trees"), oapi.Route("trees.Create", "description"), func(in EndpointInput[any, any, any, struct {
+		Obj  objTree  `json:"obj"`
+		List listTree `json:"list"`
+	}]) (res DataResponse[SingleItemData[string]], err error) {
+		return res, nil
+	})
+	obj, ok := swag.Components.Schemas["objTree"]
+	if !ok {
+		t.Fatal("missing objTree schema")
+	}
+	if ref := obj.Value.AdditionalProperties.Schema.Ref; ref != "#/components/schemas/objTree" {
+		t.Errorf("expected objTree values to reference objTree, got %q", ref)
+	}
+	list, ok := swag.Components.Schemas["listTree"]
+	if !ok {
+		t.Fatal("missing listTree schema")
+	}
+	if ref := list.Value.Items.Ref; ref != "#/components/schemas/listTree" {
+		t.Errorf("expected listTree items to reference listTree, got %q", ref)
+	}
+}
+
+func TestComplexNumbers(t *testing.T) {
+	type body struct {
+		Impedance complex128 `json:"impedance"`
+	}
+	if _, err := makeParams[body]("query", ReadableSchemaName); err == nil {
+		t.Error("complex parameter accepted")
+	}
+
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	defer func() {
+		if recover() == nil {
+			t.Error("complex body accepted")
+		}
+	}()
+	Gorilla(Post("/api/circuit"), oapi.Route("circuit.Create", "description"), func(in EndpointInput[any, any, any, body]) (res DataResponse[SingleItemData[string]], err error) {
+		return res, nil
+	})
+}
+
+func TestEnums(t *testing.T) {
+	type query struct {
+		Status status `json:"status,omitempty"`
+	}
+	type body struct {
+		Priority priority `json:"priority"`
+		Plan     string   `json:"plan" enum:"free,pro"`
+		Labels   []status `json:"labels"`
+		Previous *status  `json:"previous"`
+	}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(Post("/api/ticket"), oapi.Route("ticket.Create", "description"), func(in EndpointInput[any, any, query, body]) (res DataResponse[SingleItemData[string]], err error) {
+		return res, nil
+	})
+	router.HandleFunc(pattern, h).Methods(method)
+
+	post := func(q, b string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ticket?"+q, strings.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("status=active", `{"priority":2,"plan":"pro","labels":["blocked"],"previous":"active"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("valid enums rejected %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = post("status=gone", `{"priority":7,"plan":"gold","labels":["active","gone"],"previous":"gone"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid enums accepted %d: %s", rec.Code, rec.Body.String())
+	}
+	var res errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	locations := []string{}
+	for _, e := range res.Error.Errors {
+		locations = append(locations, *e.Location)
+	}
+	if strings.Join(locations, ",") != "status,priority,plan,labels[1],previous" {
+		t.Errorf("unexpected failing fields: %v", locations)
+	}
+
+	op := oapi.T().Paths["/api/ticket"].Post
+	if enum := op.Parameters[0].Value.Schema.Value.Enum; fmt.Sprint(enum) != "[active blocked]" {
+		t.Errorf("unexpected status enum %v", enum)
+	}
+	props := op.RequestBody.Value.Content.Get("application/json").Schema.Value.Properties
+	expected := map[string]string{
+		"priority": "[1 2 3]",
+		"plan":     "[free pro]",
+		"previous": "[active blocked]",
+	}
+	for name, enum := range expected {
+		if got := fmt.Sprint(props[name].Value.Enum); got != enum {
+			t.Errorf("expected %s enum %s, got %s", name, enum, got)
+		}
+	}
+	if got := fmt.Sprint(props["labels"].Value.Items.Value.Enum); got != "[active blocked]" {
+		t.Errorf("unexpected labels enum %s", got)
+	}
+}
+
+func TestOneOf(t *testing.T) {
+	quick_schema.RegisterOneOf[event]("kind", map[string]event{
+		"photo": photoEvent{},
+		"note":  &noteEvent{},
+	})
+	type body struct {
+		Events []event `json:"events"`
+	}
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	router := mux.NewRouter()
+	method, pattern, h := Gorilla(Post("/api/events"), oapi.Route("event.Create", "description"), func(in EndpointInput[any, any, any, body]) (res DataResponse[CollectionItemData[event]], err error) {
+		res.Data.Items = in.Body.Events
+		return res, nil
+	})
+	router.HandleFunc(pattern, h).Methods(method)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	post := func(b string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post(`{"events":[{"kind":"video"}]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown variant accepted %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(`{"events":[{"kind":"note","text":""}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid variant accepted %d: %s", rec.Code, rec.Body.String())
+	}
+
+	res, err := Call[any, any, any, body, CollectionItemData[event]](context.Background(), Client{BaseURL: server.URL}, Post("/api/events"), EndpointInput[any, any, any, body]{
+		Body: body{Events: []event{
+			photoEvent{DataDetail: DataDetail{Kind: "photo"}, URL: "https://example.com/a.png"},
+			&noteEvent{DataDetail: DataDetail{Kind: "note"}, Text: "hello"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data.Items) != 2 {
+		t.Fatalf("expected 2 events, got %+v", res.Data.Items)
+	}
+	if p, ok := res.Data.Items[0].(photoEvent); !ok || p.URL != "https://example.com/a.png" {
+		t.Errorf("expected a photo, got %#v", res.Data.Items[0])
+	}
+	if n, ok := res.Data.Items[1].(*noteEvent); !ok || n.Text != "hello" {
+		t.Errorf("expected a note, got %#v", res.Data.Items[1])
+	}
+
+	swag := oapi.T()
+	items := swag.Components.Schemas["CollectionItemDataEvent"].Value.Properties["items"].Value.Items.Value
+	if len(items.OneOf) != 2 || items.Discriminator == nil || items.Discriminator.PropertyName != "kind" {
+		t.Fatalf("expected a oneOf with a kind discriminator, got %+v", items)
+	}
+	expected := map[string]string{"note": "#/components/schemas/noteEvent", "photo": "#/components/schemas/photoEvent"}
+	for value, ref := range expected {
+		if items.Discriminator.Mapping[value] != ref {
+			t.Errorf("expected %s mapped to %s, got %s", value, ref, items.Discriminator.Mapping[value])
+		}
+		if _, ok := swag.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]; !ok {
+			t.Errorf("missing schema %s", ref)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package endpoint
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Error is an error the adapters render as a JSONC error response ({"error":{...}}) with Code as HTTP status
+type Error struct {
+	// HTTP status code of the response
+	Code int
+	// Human readable message of the error
+	Message string
+	// Details of each individual error
+	Details []ErrorDetail
+	// Underlying error, it is not sent to the client
+	Err error
+}
+
+// ErrorDetail is an entry of the "errors" array of a JSONC error response
+type ErrorDetail struct {
+	// Unique identifier for the service raising this error
+	Domain string
+	// Unique identifier for this error
+	Reason string
+	// Human readable message of the error
+	Message string
+	// The location of the error, e.g. the name of the parameter or field
+	Location string
+	// How the location should be interpreted, e.g. "path", "query" or "body"
+	LocationType string
+	// URI for a help text that might shed some more light on the error
+	ExtendedHelp string
+	// URI for a report form used by the service to collect data about the error condition
+	SendReport string
+}
+
+// NewError returns an Error with HTTP status code and message
+//
+//	endpoint.NewError(http.StatusNotFound, "collection not found",
+//		endpoint.Detail("collection", "notFound", "collection 3 does not exist").At("id", "path"))
+func NewError(code int, message string, details ...ErrorDetail) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Details: details,
+	}
+}
+
+// Detail returns an ErrorDetail for the domain raising the error
+func Detail(domain, reason, message string) ErrorDetail {
+	return ErrorDetail{
+		Domain:  domain,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// At sets the location of the error
+func (d ErrorDetail) At(location, locationType string) ErrorDetail {
+	d.Location = location
+	d.LocationType = locationType
+	return d
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap sets err as the underlying cause of e
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+func (e *Error) response() errorResponse {
+	r := errorResponse{
+		Error: generalError{
+			Code:    int64(e.Code),
+			Message: e.Message,
+		},
+	}
+	for _, d := range e.Details {
+		r.Error.Errors = append(r.Error.Errors, detailError{
+			Domain:       d.Domain,
+			Reason:       d.Reason,
+			Message:      d.Message,
+			Location:     optional(d.Location),
+			LocationType: optional(d.LocationType),
+			ExtendedHelp: optional(d.ExtendedHelp),
+			SendReport:   optional(d.SendReport),
+		})
+	}
+	return r
+}
+
+// asError reads a JSONC error response back into an Error
+func (r errorResponse) asError() *Error {
+	e := NewError(int(r.Error.Code), r.Error.Message)
+	for _, d := range r.Error.Errors {
+		e.Details = append(e.Details, ErrorDetail{
+			Domain:       d.Domain,
+			Reason:       d.Reason,
+			Message:      d.Message,
+			Location:     value(d.Location),
+			LocationType: value(d.LocationType),
+			ExtendedHelp: value(d.ExtendedHelp),
+			SendReport:   value(d.SendReport),
+		})
+	}
+	return e
+}
+
+// AsError returns err as an *Error, errors that aren't one become an internal server error,
+// their text is kept in Err and not sent to the client
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return NewError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)).Wrap(err)
+}
+
+func badRequest(err error, locationType string) *Error {
+	if errors.As(err, new(*http.MaxBytesError)) {
+		return NewError(
+			http.StatusRequestEntityTooLarge,
+			"request "+locationType+" too large",
+			Detail("global", "tooLarge", err.Error()).At("", locationType),
+		).Wrap(err)
+	}
+	return NewError(
+		http.StatusBadRequest,
+		"invalid request "+locationType,
+		Detail("global", "parseError", err.Error()).At("", locationType),
+	).Wrap(err)
+}
+
+func unsupportedContentType(contt string, accepted []string) *Error {
+	quoted := make([]string, len(accepted))
+	for i, a := range accepted {
+		quoted[i] = `"` + a + `"`
+	}
+	must := quoted[len(quoted)-1]
+	if len(quoted) > 1 {
+		must = strings.Join(quoted[:len(quoted)-1], ", ") + " or " + must
+	}
+	msg := `unsupported content-type ` + contt + `, must be ` + must
+	return NewError(
+		http.StatusUnsupportedMediaType,
+		msg,
+		Detail("global", "unsupportedMediaType", msg).At("Content-Type", "header"),
+	)
+}
+
+func optional(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return &s
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
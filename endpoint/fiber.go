@@ -1,79 +1,163 @@
 package endpoint
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
 )
 
+// FiberErrorHandler writes errors as JSONC error responses, the Fiber adapters return the errors to the app,
+// set it as the fiber.Config ErrorHandler
+func FiberErrorHandler(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if !errors.As(err, new(*Error)) && errors.As(err, &fe) {
+		err = NewError(fe.Code, fe.Message).Wrap(err)
+	}
+	e := AsError(err)
+	return c.Status(e.Code).JSON(e.response())
+}
+
+// fiberParams reads the header and cookies of the request
+func fiberParams(c *fiber.Ctx) paramValues {
+	src := paramValues{
+		header:  http.Header{},
+		cookies: map[string][]string{},
+	}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		src.header.Add(string(key), string(value))
+	})
+	c.Request().Header.VisitAllCookie(func(key, value []byte) {
+		src.cookies[string(key)] = append(src.cookies[string(key)], string(value))
+	})
+	return src
+}
+
+// fiberContext is the context of a FiberCtx handler, fasthttp doesn't cancel c.UserContext()
+// when the client disconnects, stop releases it once the handler returns
+func fiberContext(c *fiber.Ctx) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(c.UserContext())
+	shutdown := c.Context().Done()
+	closed := watchConn(c.Context().Conn())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-shutdown:
+		case <-closed:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	return ctx, func() {
+		cancel()
+		<-done
+	}
+}
+
 func Fiber[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next Endpoint[C, P, Q, B, D]) (string, string, fiber.Handler) {
+	// the handler doesn't see the context, the connection isn't watched
+	return fiberHandler(p, d, next.withContext(), func(c *fiber.Ctx) (context.Context, func()) {
+		return c.UserContext(), func() {}
+	})
+}
+
+// FiberCtx is like Fiber, the handler receives c.UserContext(),
+// cancelled when the client closes the connection or the server shuts down
+func FiberCtx[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointCtx[C, P, Q, B, D]) (string, string, fiber.Handler) {
+	return fiberHandler(p, d, next, fiberContext)
+}
+
+// fiberHandler builds the handler shared by Fiber and FiberCtx, handlerCtx gives the context next runs with
+func fiberHandler[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointCtx[C, P, Q, B, D], handlerCtx func(c *fiber.Ctx) (ctx context.Context, stop func())) (string, string, fiber.Handler) {
 
-	fillOpenAPIRoute[C, P, Q, B, D](endpointPath{
-		verb: p.verb,
-		path: routerPathToOpenAPIPath(p.path),
-	}, d)
+	rdesc := fillOpenAPIRoute[C, P, Q, B, D](p.withPath(routerPathToOpenAPIPath(p.path)), d)
 
 	return string(p.verb), p.path, func(c *fiber.Ctx) error {
 
-		if _, ok := Find([]string{http.MethodGet, http.MethodConnect, http.MethodHead, http.MethodTrace, http.MethodOptions}, string(p.verb)); !ok {
-			contt := strings.Split(string(c.Request().Header.ContentType()), ";")[0]
-			switch contt {
-			case "application/json", "application/x-www-form-urlencoded", "multipart/form-data":
-			default:
-				return errors.Errorf(`unsupported content-type %s, must be "application/json" or "application/x-www-form-urlencoded" `, contt)
+		// the body was read by fasthttp, up to the app's BodyLimit
+		readBody := rdesc.readsBody(len(c.Body()) > 0)
+		if readBody {
+			if err := rdesc.checkContentType(string(c.Request().Header.ContentType())); err != nil {
+				return err
 			}
 		}
 
-		var cc C
-		user, ok := c.Locals("user").(*jwt.Token)
-		if ok {
-			claims, ok1 := user.Claims.(jwt.MapClaims)
-			if ok1 {
-				var err error
-				cc, err = mapToStruct(claims, *new(C))
-				if err != nil {
-					return errors.Wrap(err, "raw claims")
-				}
-			}
+		cc, err := resolveClaims[C](rdesc, fiberClaimsSource{c: c})
+		if err != nil {
+			return err
 		}
 
-		m := map[string]string{}
-		for _, p := range c.Route().Params {
-			m[p] = c.Params(p)
-		}
-		p, err := mapToStruct(m, *new(P))
+		src := fiberParams(c)
+		src.values = pathValues[P](func(name string) string {
+			return c.Params(name)
+		})
+		prs, err := decodeValues[P](src, "path")
 		if err != nil {
-			return errors.Wrap(err, "claims")
+			return err
 		}
 
-		q := new(Q)
-		err = c.QueryParser(q)
+		src.values = url.Values{}
+		c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+			src.values[string(key)] = append(src.values[string(key)], string(value))
+		})
+		q, err := decodeValues[Q](src, "query")
 		if err != nil {
-			return errors.Wrap(err, "query")
+			return err
 		}
 
 		b := new(B)
-		if len(c.Body()) > 0 {
-			err = c.BodyParser(b)
-			if err != nil {
-				return errors.Wrap(err, "body")
+		if readBody {
+			if strings.HasPrefix(string(c.Request().Header.ContentType()), "multipart/form-data") {
+				form, err := c.MultipartForm()
+				if err != nil {
+					return badRequest(err, "body")
+				}
+				b, err = decodeMultipart[B](form)
+				if err != nil {
+					return err
+				}
+			} else if hasOneOf(reflect.TypeOf(b).Elem()) {
+				err = unmarshalJSON(c.Body(), b)
+				if err != nil {
+					return badRequest(err, "body")
+				}
+			} else {
+				err = c.BodyParser(b)
+				if err != nil {
+					return badRequest(err, "body")
+				}
 			}
 		}
 
 		input := EndpointInput[C, P, Q, B]{
 			Claims: cc,
-			Params: p,
-			Query:  *q,
+			Params: prs,
+			Query:  q,
 			Body:   *b,
 		}
 
-		r, err := next(input)
+		if err := validateInput(input); err != nil {
+			return err
+		}
+
+		ctx, stop := handlerCtx(c)
+		defer stop()
+		r, err := next(ctx, input)
 		if err != nil {
 			return err
 		}
-		return c.JSON(r)
+		if len(r.location) > 0 {
+			c.Set(fiber.HeaderLocation, r.location)
+		}
+		status := p.successStatus()
+		if status == http.StatusNoContent {
+			return c.SendStatus(http.StatusNoContent)
+		}
+		return c.Status(status).JSON(r)
 	}
 }
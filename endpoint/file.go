@@ -0,0 +1,241 @@
+package endpoint
+
+import (
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
+
+// File is a file uploaded in a multipart/form-data request body, documented as a binary string.
+// Size and content-type limits are declared with the "file" struct tag:
+//
+//	type Upload struct {
+//		Avatar    endpoint.File   `json:"avatar" validate:"required" file:"maxSize=1048576,accept=image/png|image/jpeg"`
+//		Documents []endpoint.File `json:"documents" validate:"maxItems=5" file:"accept=application/pdf|image/*"`
+//	}
+type File struct {
+	*multipart.FileHeader
+}
+
+func (File) Node() *quick_schema.Node {
+	return &quick_schema.Node{
+		Type:   "binary",
+		Format: "string",
+	}
+}
+
+// defaultMultipartMemory is the amount of a multipart body kept in memory, the rest is stored in temporary files
+const defaultMultipartMemory = 32 << 20
+
+// defaultMaxBodySize is the largest request body of routes without file fields,
+// routes with file fields take their files' maxSize on top of it
+const defaultMaxBodySize = 32 << 20
+
+var fileType = reflect.TypeOf(File{})
+
+type fileRules struct {
+	maxSize int64
+	accept  []string
+	// files the field holds, 1 or the maxItems of slices
+	maxFiles int64
+}
+
+func parseFileRules(tag reflect.StructTag) (rules fileRules, err error) {
+	for _, rule := range strings.Split(tag.Get("file"), ",") {
+		rule = strings.TrimSpace(rule)
+		if len(rule) == 0 {
+			continue
+		}
+		k, v, _ := strings.Cut(rule, "=")
+		switch k {
+		case "maxSize":
+			rules.maxSize, err = strconv.ParseInt(v, 10, 64)
+		case "accept":
+			rules.accept = strings.Split(v, "|")
+		default:
+			err = errors.New("unknown rule")
+		}
+		if err != nil {
+			return rules, errors.Wrapf(err, "invalid file rule \"%s\"", rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r fileRules) check(fh *multipart.FileHeader) error {
+	if r.maxSize > 0 && fh.Size > r.maxSize {
+		return errors.Errorf("%s is larger than %d bytes", fh.Filename, r.maxSize)
+	}
+	if len(r.accept) == 0 {
+		return nil
+	}
+	ct, _, _ := mime.ParseMediaType(fh.Header.Get("Content-Type"))
+	for _, a := range r.accept {
+		if ok, _ := path.Match(a, ct); ok {
+			return nil
+		}
+	}
+	return errors.Errorf("%s has content-type %s, must be %s", fh.Filename, ct, strings.Join(r.accept, " or "))
+}
+
+// isFileField tells whether a field holds uploaded files: File, *File or []File
+func isFileField(t reflect.Type) bool {
+	t = indirectType(t)
+	if t.Kind() == reflect.Slice {
+		t = indirectType(t.Elem())
+	}
+	return t == fileType
+}
+
+// fileFields lists T's File fields, by name, with their rules
+func fileFields(t reflect.Type) (map[string]fileRules, error) {
+	fields := map[string]fileRules{}
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return fields, nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, ok := quick_schema.FieldName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+			nested, err := fileFields(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			for n, r := range nested {
+				fields[n] = r
+			}
+			continue
+		}
+		if !isFileField(f.Type) {
+			continue
+		}
+		rules, err := parseFileRules(f.Tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s.%s", t.Name(), f.Name)
+		}
+		rules.maxFiles = 1
+		// invalid constraints are reported by checkConstraintTags
+		if c, _ := quick_schema.ParseConstraints(f.Tag); c != nil && c.MaxItems != nil && indirectType(f.Type).Kind() == reflect.Slice {
+			rules.maxFiles = int64(*c.MaxItems)
+		}
+		fields[name] = rules
+	}
+	return fields, nil
+}
+
+// maxBodySize is the largest request body of a route with the file fields
+func maxBodySize(files map[string]fileRules) int64 {
+	size := int64(defaultMaxBodySize)
+	for _, r := range files {
+		size += r.maxSize * r.maxFiles
+	}
+	return size
+}
+
+// decodeMultipart builds a B from a multipart form, values are coerced like query values
+// and File fields are filled with the uploaded files
+func decodeMultipart[B any](form *multipart.Form) (*B, error) {
+	b, err := decodeValues[B](paramValues{values: form.Value}, "body")
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(&b).Elem()
+	if v.Kind() == reflect.Interface {
+		return &b, nil
+	}
+	details := bindFiles(v, form.File)
+	if len(details) > 0 {
+		return nil, NewError(http.StatusUnprocessableEntity, "invalid files", details...)
+	}
+	return &b, nil
+}
+
+func bindFiles(v reflect.Value, files map[string][]*multipart.FileHeader) (details []ErrorDetail) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, ok := quick_schema.FieldName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+			details = append(details, bindFiles(v.Field(i), files)...)
+			continue
+		}
+		if !isFileField(f.Type) || len(files[name]) == 0 {
+			continue
+		}
+		// invalid tags are reported when the route is registered
+		rules, _ := parseFileRules(f.Tag)
+		uploaded := []reflect.Value{}
+		for _, fh := range files[name] {
+			if err := rules.check(fh); err != nil {
+				details = append(details, Detail("global", "invalidFile", fmt.Sprintf("%s: %s", name, err.Error())).At(name, "body"))
+				continue
+			}
+			uploaded = append(uploaded, reflect.ValueOf(File{FileHeader: fh}))
+		}
+		if len(uploaded) == 0 {
+			continue
+		}
+		fv := v.Field(i)
+		switch indirectType(f.Type).Kind() {
+		case reflect.Slice:
+			sl := reflect.MakeSlice(indirectType(f.Type), 0, len(uploaded))
+			for _, u := range uploaded {
+				if sl.Type().Elem().Kind() == reflect.Pointer {
+					p := reflect.New(fileType)
+					p.Elem().Set(u)
+					u = p
+				}
+				sl = reflect.Append(sl, u)
+			}
+			setIndirect(fv, sl)
+		default:
+			setIndirect(fv, uploaded[0])
+		}
+	}
+	return details
+}
+
+func setIndirect(v, x reflect.Value) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	v.Set(x)
+}
+
+// multipartEncoding documents the content-types accepted by T's File fields
+func multipartEncoding(mt *openapi3.MediaType, fields map[string]fileRules) *openapi3.MediaType {
+	for name, rules := range fields {
+		if len(rules.accept) > 0 {
+			mt.WithEncoding(name, &openapi3.Encoding{ContentType: strings.Join(rules.accept, ", ")})
+		}
+	}
+	return mt
+}
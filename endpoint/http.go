@@ -0,0 +1,130 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+func writeErrJSON(w http.ResponseWriter, err error) {
+	e := AsError(err)
+	b, err := json.Marshal(e.response())
+	if err != nil {
+		b = []byte(err.Error())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Code)
+	w.Write(b)
+}
+
+func writeJSON[T any](w http.ResponseWriter, statusCode int, data T) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		writeErrJSON(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
+
+// decodeBody reads the request body according to its content-type
+func decodeBody[B any](req *http.Request) (*B, error) {
+	switch strings.Split(req.Header.Get("Content-Type"), ";")[0] {
+	case "multipart/form-data":
+		if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+			return nil, badRequest(err, "body")
+		}
+		return decodeMultipart[B](req.MultipartForm)
+	case "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return nil, badRequest(err, "body")
+		}
+		b, err := decodeValues[B](paramValues{values: req.PostForm}, "body")
+		return &b, err
+	}
+	b := new(B)
+	if err := decodeJSON(req.Body, b); err != nil {
+		return nil, badRequest(err, "body")
+	}
+	return b, nil
+}
+
+// httpHandler builds the net/http handler shared by the Gorilla and StdHTTP adapters,
+// pathValue reads a path parameter the way the router stores it
+func httpHandler[C, P, Q, B any, D dataer](p endpointPath, rdesc RouteDescription, next EndpointCtx[C, P, Q, B, D], pathValue func(req *http.Request, name string) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+
+		readBody := rdesc.readsBody(req.ContentLength != 0)
+		if readBody {
+			if err := rdesc.checkContentType(req.Header.Get("Content-Type")); err != nil {
+				writeErrJSON(w, err)
+				return
+			}
+		}
+
+		cc, err := resolveClaims[C](rdesc, httpClaimsSource{req: req})
+		if err != nil {
+			writeErrJSON(w, err)
+			return
+		}
+
+		prs, err := decodeValues[P](requestParams(req, pathValues[P](func(name string) string {
+			return pathValue(req, name)
+		})), "path")
+		if err != nil {
+			writeErrJSON(w, err)
+			return
+		}
+
+		q, err := decodeValues[Q](requestParams(req, req.URL.Query()), "query")
+		if err != nil {
+			writeErrJSON(w, err)
+			return
+		}
+
+		b := new(B)
+		if readBody {
+			req.Body = http.MaxBytesReader(w, req.Body, rdesc.maxBodySize)
+			b, err = decodeBody[B](req)
+			if err != nil {
+				writeErrJSON(w, err)
+				return
+			}
+		}
+
+		input := EndpointInput[C, P, Q, B]{
+			Claims: cc,
+			Params: prs,
+			Query:  q,
+			Body:   *b,
+		}
+
+		if err := validateInput(input); err != nil {
+			writeErrJSON(w, err)
+			return
+		}
+
+		r, err := next(req.Context(), input)
+		if err != nil {
+			writeErrJSON(w, err)
+			return
+		}
+		if len(r.location) > 0 {
+			w.Header().Set("Location", r.location)
+		}
+		if p.successStatus() == http.StatusNoContent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, p.successStatus(), r)
+	}
+}
+
+// serveMuxPathToOpenAPIPath transforms ServeMux patterns into the OpenAPI format:
+// "{param...}" -> "{param}" and the "{$}" end anchor is dropped
+func serveMuxPathToOpenAPIPath(path string) string {
+	path = strings.ReplaceAll(path, "{$}", "")
+	return strings.ReplaceAll(path, "...}", "}")
+}
@@ -0,0 +1,173 @@
+package endpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decodeJSON decodes a JSON document into v, interfaces registered with quick_schema.RegisterOneOf
+// are decoded into the variant their discriminator names
+func decodeJSON(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return unmarshalJSON(b, v)
+}
+
+func unmarshalJSON(b []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || !hasOneOf(rv.Type().Elem()) {
+		return json.Unmarshal(b, v)
+	}
+	return unmarshalOneOf(b, rv.Elem())
+}
+
+// hasOneOf tells if values of t hold registered interfaces they don't decode themselves
+func hasOneOf(t reflect.Type) bool {
+	return !reflect.PointerTo(t).Implements(unmarshalerType) && quick_schema.HoldsOneOf(t)
+}
+
+// unmarshalOneOf decodes b into v, walking v's type down to its registered interfaces
+func unmarshalOneOf(b []byte, v reflect.Value) error {
+	if !hasOneOf(v.Type()) {
+		return json.Unmarshal(b, v.Addr().Interface())
+	}
+	if string(bytes.TrimSpace(b)) == "null" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		o, _ := quick_schema.OneOfType(v.Type())
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return err
+		}
+		var d string
+		if raw, ok := fields[o.Discriminator]; !ok || json.Unmarshal(raw, &d) != nil {
+			return errors.Errorf("missing %s", o.Discriminator)
+		}
+		t, ok := o.Variants[d]
+		if !ok {
+			return errors.Errorf("unknown %s \"%s\"", o.Discriminator, d)
+		}
+		x := reflect.New(t).Elem()
+		if t.Kind() == reflect.Pointer {
+			x.Set(reflect.New(t.Elem()))
+		}
+		if err := unmarshalOneOf(b, x); err != nil {
+			return err
+		}
+		v.Set(x)
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalOneOf(b, v.Elem())
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(b, &items); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := unmarshalOneOf(items[i], v.Index(i)); err != nil {
+				return errors.Wrap(err, strconv.Itoa(i))
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return errors.Errorf("unsupported map key %s", v.Type().Key().String())
+		}
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(b, &items); err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(items))
+		for k, raw := range items {
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := unmarshalOneOf(raw, e); err != nil {
+				return errors.Wrap(err, k)
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), e)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return err
+		}
+		_, err := unmarshalFields(fields, v)
+		return err
+	default:
+		return json.Unmarshal(b, v.Addr().Interface())
+	}
+	return nil
+}
+
+// unmarshalFields decodes the fields of a JSON object into a struct, names are matched like encoding/json does,
+// returns whether any was set
+func unmarshalFields(fields map[string]json.RawMessage, v reflect.Value) (set bool, err error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, ok := quick_schema.FieldName(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+			if fv.Kind() != reflect.Pointer {
+				embedded, err := unmarshalFields(fields, fv)
+				if err != nil {
+					return set, err
+				}
+				set = set || embedded
+				continue
+			}
+			// embedded pointers are only allocated if one of their fields is set
+			e := fv
+			if e.IsNil() {
+				e = reflect.New(fv.Type().Elem())
+			}
+			embedded, err := unmarshalFields(fields, e.Elem())
+			if err != nil {
+				return set, err
+			}
+			if embedded && fv.IsNil() {
+				fv.Set(e)
+			}
+			set = set || embedded
+			continue
+		}
+		raw, ok := fields[name]
+		if !ok {
+			for k, r := range fields {
+				if strings.EqualFold(k, name) {
+					raw, ok = r, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := unmarshalOneOf(raw, fv); err != nil {
+			return set, errors.Wrap(err, name)
+		}
+		set = true
+	}
+	return set, nil
+}
@@ -0,0 +1,183 @@
+package endpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
+
+// OpenAPI versions documents can be encoded in
+const (
+	OpenAPI30 = "3.0.0"
+	OpenAPI31 = "3.1.0"
+)
+
+// SetVersion sets the OpenAPI version MarshalJSON encodes the document in, OpenAPI30 by default.
+// The document T returns stays an OpenAPI 3.0 one, 3.1 documents are converted when encoded:
+// nullable schemas add "null" to their type, examples are lists and webhooks aren't an extension
+func (op *OpenAPI) SetVersion(v string) {
+	if v != OpenAPI30 && v != OpenAPI31 {
+		panic("unsupported OpenAPI version: " + v)
+	}
+	op.version = v
+}
+
+// MarshalJSON encodes the document in the OpenAPI version set with SetVersion, json.Marshal(oapi) does too
+func (op OpenAPI) MarshalJSON() ([]byte, error) {
+	b, err := op.t.MarshalJSON()
+	if err != nil || op.version != OpenAPI31 {
+		return b, err
+	}
+	var doc map[string]any
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	doc["openapi"] = OpenAPI31
+	if w, ok := doc["x-webhooks"]; ok {
+		delete(doc, "x-webhooks")
+		doc["webhooks"] = w
+	}
+	convert31(doc)
+	return json.Marshal(doc)
+}
+
+// Webhook documents a request the API sends to its subscribers, with a B body, as the webhook name.
+// OpenAPI 3.0 has no webhooks, they're listed in the x-webhooks extension:
+//
+//	endpoint.Webhook[OrderCreated]("orderCreated", oapi.Route("Order created", "Sent once an order is paid"))
+func Webhook[B any](name string, d OpenAPIRouteDescriber) {
+	d(func(rdesc RouteDescription, swag *openapi3.T) {
+		if swag.Extensions == nil {
+			swag.Extensions = map[string]interface{}{}
+		}
+		webhooks, _ := swag.Extensions["x-webhooks"].(map[string]*openapi3.PathItem)
+		if webhooks == nil {
+			webhooks = map[string]*openapi3.PathItem{}
+			swag.Extensions["x-webhooks"] = webhooks
+		}
+		if webhooks[name] != nil {
+			panic("webhook already exists: " + name)
+		}
+		schemas := rdesc.schemas
+		if schemas == nil {
+			schemas = newSchemaRegistry()
+		}
+
+		desc := "webhook received"
+		op := &openapi3.Operation{
+			Summary:     rdesc.Title,
+			Description: rdesc.Description,
+			OperationID: toCamelCase(rdesc.Title),
+			Responses: openapi3.Responses{
+				"200": &openapi3.ResponseRef{
+					Value: &openapi3.Response{Description: &desc},
+				},
+			},
+		}
+		if n := quick_schema.GetSchema[B](); n != nil {
+			bodyRepo := schemas.build(*n)
+			if err := schemas.register(swag, bodyRepo); err != nil {
+				panic(errors.Wrap(err, "bad webhook data"))
+			}
+			op.RequestBody = &openapi3.RequestBodyRef{
+				Value: &openapi3.RequestBody{
+					Description: "Webhook data",
+					Content:     openapi3.NewContentWithJSONSchema(bodyRepo.Start),
+				},
+			}
+		}
+		if len(rdesc.Tag) > 0 {
+			op.Tags = []string{rdesc.Tag}
+		}
+		webhooks[name] = &openapi3.PathItem{Post: op}
+	})
+}
+
+// convert31 converts the schemas of an OpenAPI 3.0 document, or of a part of it, to OpenAPI 3.1.
+// Examples and extensions hold data, not documentation, and are left as they are
+func convert31(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			switch {
+			case k == "example", k == "examples", k == "default", strings.HasPrefix(k, "x-"):
+			case k == "schema":
+				v[k] = schema31(x)
+			case k == "schemas":
+				if m, ok := x.(map[string]any); ok {
+					for name, s := range m {
+						m[name] = schema31(s)
+					}
+				}
+			default:
+				convert31(x)
+			}
+		}
+	case []any:
+		for _, x := range v {
+			convert31(x)
+		}
+	}
+}
+
+// schema31 converts an OpenAPI 3.0 schema to JSON Schema 2020-12
+func schema31(v any) any {
+	s, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	if p, ok := s["properties"].(map[string]any); ok {
+		for name, x := range p {
+			p[name] = schema31(x)
+		}
+	}
+	for _, k := range []string{"items", "additionalProperties", "not", "x-propertyNames"} {
+		if x, ok := s[k]; ok {
+			s[k] = schema31(x)
+		}
+	}
+	for _, k := range []string{"oneOf", "anyOf", "allOf"} {
+		if l, ok := s[k].([]any); ok {
+			for i, x := range l {
+				l[i] = schema31(x)
+			}
+		}
+	}
+	if x, ok := s["x-propertyNames"]; ok {
+		delete(s, "x-propertyNames")
+		s["propertyNames"] = x
+	}
+	if x, ok := s["example"]; ok {
+		delete(s, "example")
+		// schemas without an example have an empty one
+		if x != "" {
+			s["examples"] = []any{x}
+		}
+	}
+	nullable, _ := s["nullable"].(bool)
+	delete(s, "nullable")
+	if !nullable {
+		return s
+	}
+	if t, ok := s["type"].(string); ok {
+		s["type"] = []any{t, "null"}
+		if e, ok := s["enum"].([]any); ok {
+			s["enum"] = append(e, nil)
+		}
+		return s
+	}
+	oneOf, _ := s["oneOf"].([]any)
+	if _, ok := s["$ref"]; !ok && len(oneOf) == 0 {
+		// any value, null included
+		return s
+	}
+	return map[string]any{
+		"anyOf": []any{s, map[string]any{"type": "null"}},
+	}
+}
@@ -1,8 +1,94 @@
 package endpoint
 
-import "github.com/getkin/kin-openapi/openapi3"
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
 
 type SchemaRepo struct {
 	Start *openapi3.Schema
 	Repo  map[string]*openapi3.Schema
+
+	// Go types of the schemas in Repo, by name
+	types map[string]quick_schema.Node
+	// set if two types got the same name
+	err error
+}
+
+// SchemaNameFunc names the component schema of a named struct type
+type SchemaNameFunc func(n quick_schema.Node) string
+
+// ReadableSchemaName names schemas by their type, generic arguments appended:
+// DataResponse[SingleItemData[Collection]] -> DataResponseSingleItemDataCollection,
+// types implementing quick_schema.SchemaNamer name themselves
+func ReadableSchemaName(n quick_schema.Node) string {
+	return n.SchemaName
+}
+
+// QualifiedSchemaName prefixes readable names with their package's name,
+// for APIs using types with the same name from different packages: endpoint.DataDetail -> EndpointDataDetail
+func QualifiedSchemaName(n quick_schema.Node) string {
+	pkg := []rune(path.Base(n.Package))
+	if len(pkg) == 0 || len(n.Package) == 0 {
+		return n.SchemaName
+	}
+	pkg[0] = unicode.ToUpper(pkg[0])
+	return strings.NewReplacer(".", "", "-", "", "_", "").Replace(string(pkg)) + n.SchemaName
+}
+
+// schemaRegistry names the schemas of an OpenAPI document and detects name collisions
+type schemaRegistry struct {
+	name SchemaNameFunc
+	// Go types of the registered schemas, by name
+	types map[string]quick_schema.Node
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		name:  ReadableSchemaName,
+		types: map[string]quick_schema.Node{},
+	}
+}
+
+// SetSchemaNamer sets how component schemas are named, ReadableSchemaName by default
+func (op *OpenAPI) SetSchemaNamer(f SchemaNameFunc) {
+	op.schemas.name = f
+}
+
+func (r *schemaRegistry) build(n quick_schema.Node) SchemaRepo {
+	return buildSchemaRepo(n, r.name)
+}
+
+// register adds the repo's schemas to the document's components,
+// returns an error if a name is already taken by another type
+func (r *schemaRegistry) register(swag *openapi3.T, repo SchemaRepo) error {
+	if repo.err != nil {
+		return repo.err
+	}
+	if swag.Components.Schemas == nil {
+		swag.Components.Schemas = openapi3.Schemas{}
+	}
+	for n, val := range repo.Repo {
+		if val == nil {
+			return errors.Errorf("unexpected nil schema %s", n)
+		}
+		// types of schemas built by a Schemaer are unknown
+		if t, ok := repo.types[n]; ok {
+			if registered, ok := r.types[n]; ok && !registered.SameType(t) {
+				return schemaCollision(n, registered, t)
+			}
+			r.types[n] = t
+		}
+		swag.Components.Schemas[n] = openapi3.NewSchemaRef("", val)
+	}
+	return nil
+}
+
+func schemaCollision(name string, a, b quick_schema.Node) error {
+	return errors.Errorf("schema name \"%s\" is used by %s.%s and %s.%s, implement quick_schema.SchemaNamer or set a SchemaNameFunc to tell them apart", name, a.Package, a.Type, b.Package, b.Type)
 }
@@ -0,0 +1,42 @@
+// Code generated by schemadoc. DO NOT EDIT.
+
+package endpoint
+
+import "github.com/pindamonhangaba/apiculi/quick_schema"
+
+func init() {
+	quick_schema.RegisterDescriptions("github.com/pindamonhangaba/apiculi/endpoint", map[string]string{
+		"Client":                            "Client calls the endpoints of a remote API",
+		"Client.BaseURL":                    "URL the routes' paths are appended to, e.g. \"https://api.example.com\"",
+		"Client.Before":                     "Before is called with each request before it's sent, e.g. to set its Authorization header",
+		"Client.HTTPClient":                 "http.DefaultClient if nil",
+		"CollectionDetail.CurrentItemCount": "The number of items in this result set",
+		"CollectionDetail.ItemsPerPage":     "The number of items in the result",
+		"CollectionDetail.PageIndex":        "The index of the current page of items",
+		"CollectionDetail.StartIndex":       "The index of the first item in data.items",
+		"CollectionDetail.TotalItems":       "The total number of items available in this set",
+		"CollectionDetail.TotalPages":       "The total number of pages in the result set.",
+		"DataDetail.Kind":                   "The kind property serves as a guide to what type of information this particular object stores",
+		"DataDetail.Language":               "Indicates the language of the rest of the properties in this object (BCP 47)",
+		"DataResponse.Context":              "Client sets this value and server echos data in the response",
+		"Error":                             "Error is an error the adapters render as a JSONC error response ({\"error\":{...}}) with Code as HTTP status",
+		"Error.Code":                        "HTTP status code of the response",
+		"Error.Details":                     "Details of each individual error",
+		"Error.Err":                         "Underlying error, it is not sent to the client",
+		"Error.Message":                     "Human readable message of the error",
+		"ErrorDetail":                       "ErrorDetail is an entry of the \"errors\" array of a JSONC error response",
+		"ErrorDetail.Domain":                "Unique identifier for the service raising this error",
+		"ErrorDetail.ExtendedHelp":          "URI for a help text that might shed some more light on the error",
+		"ErrorDetail.Location":              "The location of the error, e.g. the name of the parameter or field",
+		"ErrorDetail.LocationType":          "How the location should be interpreted, e.g. \"path\", \"query\" or \"body\"",
+		"ErrorDetail.Message":               "Human readable message of the error",
+		"ErrorDetail.Reason":                "Unique identifier for this error",
+		"ErrorDetail.SendReport":            "URI for a report form used by the service to collect data about the error condition",
+		"File":                              "File is a file uploaded in a multipart/form-data request body, documented as a binary string.",
+		"RouteDescription.Errors":           "HTTP status codes of the errors the route may return, besides the default ones",
+		"RouteDescription.OptionalClaims":   "Requests without claims are let through",
+		"RouteDescription.Public":           "The route is not authenticated",
+		"RouteDescription.Security":         "Alternative security requirements, one of them must be met",
+		"SecurityRequirement":               "SecurityRequirement is a security scheme registered in the OpenAPI document and the scopes, or roles, the claims must have to access a route",
+	})
+}
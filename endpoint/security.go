@@ -0,0 +1,332 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// SecurityRequirement is a security scheme registered in the OpenAPI document
+// and the scopes, or roles, the claims must have to access a route
+type SecurityRequirement struct {
+	Scheme string
+	Scopes []string
+}
+
+// Scoper is implemented by claims that carry scopes or roles,
+// otherwise they are read from the claims' "scope", "scp", "scopes" or "roles" fields
+type Scoper interface {
+	Scopes() []string
+}
+
+// WithSecurity requires the route's claims to be authenticated by scheme and have all the scopes,
+// each WithSecurity is an alternative requirement, overrides the security of the route's group
+func WithSecurity(scheme string, scopes ...string) RouteOption {
+	return func(r *RouteDescription) {
+		r.Public = false
+		r.Security = append(r.Security, SecurityRequirement{Scheme: scheme, Scopes: scopes})
+	}
+}
+
+// Public documents the route as not authenticated, overrides the security of the route's group,
+// the route's claims are optional
+func Public() RouteOption {
+	return func(r *RouteDescription) {
+		r.Public = true
+		r.Security = nil
+	}
+}
+
+// With sets options applied to every route in the group, before the route's own
+func (g OpenAPIRouteGroup) With(opts ...RouteOption) OpenAPIRouteGroup {
+	g.opts = append(append([]RouteOption{}, g.opts...), opts...)
+	return g
+}
+
+// securityRequirements documents the route's security, nil if it inherits the document's
+func (r RouteDescription) securityRequirements(swag *openapi3.T) (*openapi3.SecurityRequirements, error) {
+	if r.Public {
+		return openapi3.NewSecurityRequirements(), nil
+	}
+	if len(r.Security) == 0 {
+		return nil, nil
+	}
+	reqs := openapi3.NewSecurityRequirements()
+	for _, s := range r.Security {
+		scheme := swag.Components.SecuritySchemes[s.Scheme]
+		if scheme == nil {
+			return nil, errors.Errorf("security scheme \"%s\" is not registered", s.Scheme)
+		}
+		if flows := scheme.Value.Flows; flows != nil {
+			for _, scope := range s.Scopes {
+				if !hasScope(flows, scope) {
+					return nil, errors.Errorf("scope \"%s\" is not declared by security scheme \"%s\"", scope, s.Scheme)
+				}
+			}
+		}
+		scopes := s.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		reqs.With(openapi3.NewSecurityRequirement().Authenticate(s.Scheme, scopes...))
+	}
+	return reqs, nil
+}
+
+func hasScope(flows *openapi3.OAuthFlows, scope string) bool {
+	for _, f := range []*openapi3.OAuthFlow{flows.Implicit, flows.Password, flows.ClientCredentials, flows.AuthorizationCode} {
+		if f != nil {
+			if _, ok := f.Scopes[scope]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r RouteDescription) scoped() bool {
+	for _, s := range r.Security {
+		if len(s.Scopes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// missingScopes are the scopes of a security requirement the claims don't have
+func (s SecurityRequirement) missingScopes(claims any) []string {
+	if len(s.Scopes) == 0 {
+		return nil
+	}
+	granted := claimScopes(claims)
+	missing := []string{}
+	for _, scope := range s.Scopes {
+		if !has(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// claimScopes reads the scopes, or roles, of the claims
+func claimScopes(claims any) []string {
+	if s, ok := claims.(Scoper); ok {
+		return s.Scopes()
+	}
+	m, ok := claims.(map[string]any)
+	if !ok {
+		b, err := json.Marshal(claims)
+		if err != nil || json.Unmarshal(b, &m) != nil {
+			return nil
+		}
+	}
+	scopes := []string{}
+	for _, k := range []string{"scope", "scp", "scopes", "roles"} {
+		switch v := m[k].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(v)...)
+		case []any:
+			for _, s := range v {
+				if s, ok := s.(string); ok {
+					scopes = append(scopes, s)
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+// TokenVerifier checks an API key or an access token, returns its claims,
+// nil claims and no error if the token is unknown
+type TokenVerifier func(token string) (any, error)
+
+// BasicVerifier checks HTTP Basic credentials, returns their claims,
+// nil claims and no error if they are unknown
+type BasicVerifier func(username, password string) (any, error)
+
+func (op *OpenAPI) addSecurityScheme(name string, s *openapi3.SecurityScheme, p ClaimsProvider) *openapi3.T {
+	if op.t.Components.SecuritySchemes == nil {
+		op.t.Components.SecuritySchemes = openapi3.SecuritySchemes{}
+	}
+	op.t.Components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{
+		Value: s,
+	}
+	if p != nil {
+		if op.schemes == nil {
+			op.schemes = map[string]ClaimsProvider{}
+		}
+		op.schemes[name] = p
+	}
+	return &op.t
+}
+
+// AddAPIKeyAuth registers an API key sent in the header, query or cookie paramName,
+// routes secured by name read their claims with verify
+func (op *OpenAPI) AddAPIKeyAuth(name, in, paramName string, verify TokenVerifier) *openapi3.T {
+	switch in {
+	case openapi3.ParameterInHeader, openapi3.ParameterInQuery, openapi3.ParameterInCookie:
+	default:
+		panic("api key can't be in " + in)
+	}
+	s := openapi3.NewSecurityScheme()
+	s.Type = "apiKey"
+	s.In = in
+	s.Name = paramName
+	return op.addSecurityScheme(name, s, APIKeyClaims(in, paramName, verify))
+}
+
+// AddBasicAuth registers HTTP Basic authentication, routes secured by name read their claims with verify
+func (op *OpenAPI) AddBasicAuth(name string, verify BasicVerifier) *openapi3.T {
+	s := openapi3.NewSecurityScheme()
+	s.Type = "http"
+	s.Scheme = "basic"
+	return op.addSecurityScheme(name, s, BasicAuthClaims(verify))
+}
+
+// OAuth2Flow documents how clients get an OAuth2 access token
+type OAuth2Flow func(*openapi3.OAuthFlows)
+
+// AuthorizationCodeFlow documents the OAuth2 authorization code flow, scopes maps each scope to its description
+func AuthorizationCodeFlow(authorizationURL, tokenURL string, scopes map[string]string) OAuth2Flow {
+	return func(f *openapi3.OAuthFlows) {
+		f.AuthorizationCode = &openapi3.OAuthFlow{
+			AuthorizationURL: authorizationURL,
+			TokenURL:         tokenURL,
+			Scopes:           scopes,
+		}
+	}
+}
+
+// ClientCredentialsFlow documents the OAuth2 client credentials flow, scopes maps each scope to its description
+func ClientCredentialsFlow(tokenURL string, scopes map[string]string) OAuth2Flow {
+	return func(f *openapi3.OAuthFlows) {
+		f.ClientCredentials = &openapi3.OAuthFlow{
+			TokenURL: tokenURL,
+			Scopes:   scopes,
+		}
+	}
+}
+
+// AddOAuth2Auth registers OAuth2 bearer access tokens, routes secured by name read their claims with verify
+// and may only require the scopes declared in the flows
+func (op *OpenAPI) AddOAuth2Auth(name string, verify TokenVerifier, flows ...OAuth2Flow) *openapi3.T {
+	s := openapi3.NewSecurityScheme()
+	s.Type = "oauth2"
+	s.Flows = &openapi3.OAuthFlows{}
+	for _, f := range flows {
+		f(s.Flows)
+	}
+	return op.addSecurityScheme(name, s, BearerClaims(verify))
+}
+
+// AddOpenIDConnectAuth registers OpenID Connect bearer tokens, discovered at discoveryURL,
+// routes secured by name read their claims with verify
+func (op *OpenAPI) AddOpenIDConnectAuth(name, discoveryURL string, verify TokenVerifier) *openapi3.T {
+	return op.addSecurityScheme(name, openapi3.NewOIDCSecurityScheme(discoveryURL), BearerClaims(verify))
+}
+
+// APIKeyClaims reads the claims of the API key sent in the header, query or cookie paramName
+func APIKeyClaims(in, paramName string, verify TokenVerifier) ClaimsProvider {
+	return ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		var key string
+		switch in {
+		case openapi3.ParameterInHeader:
+			key = src.Header(paramName)
+		case openapi3.ParameterInQuery:
+			key = src.Query(paramName)
+		case openapi3.ParameterInCookie:
+			key = src.Cookie(paramName)
+		}
+		if len(key) == 0 || verify == nil {
+			return nil, nil
+		}
+		return verify(key)
+	})
+}
+
+// BasicAuthClaims reads the claims of the HTTP Basic credentials in the Authorization header
+func BasicAuthClaims(verify BasicVerifier) ClaimsProvider {
+	return ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		req := http.Request{Header: http.Header{"Authorization": {src.Header("Authorization")}}}
+		username, password, ok := req.BasicAuth()
+		if !ok || verify == nil {
+			return nil, nil
+		}
+		return verify(username, password)
+	})
+}
+
+// BearerClaims reads the claims of the bearer token in the Authorization header
+func BearerClaims(verify TokenVerifier) ClaimsProvider {
+	return ClaimsProviderFunc(func(src ClaimsSource) (any, error) {
+		auth := src.Header("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") || verify == nil {
+			return nil, nil
+		}
+		return verify(strings.TrimSpace(auth[7:]))
+	})
+}
+
+// routeClaimsProvider is the route's claims provider, the OpenAPI's one if it has none, or the default one
+func (r RouteDescription) routeClaimsProvider() ClaimsProvider {
+	if r.claims != nil {
+		return r.claims
+	}
+	if r.api != nil && r.api.claims != nil {
+		return r.api.claims
+	}
+	return defaultClaimsProvider
+}
+
+// schemeClaimsProvider is the claims provider of the security scheme, nil if it has none
+func (r RouteDescription) schemeClaimsProvider(scheme string) ClaimsProvider {
+	if r.api == nil {
+		return nil
+	}
+	return r.api.schemes[scheme]
+}
+
+// authorizedClaims reads the claims with the provider of each of the route's security requirements' scheme,
+// returns the first claims that have their requirement's scopes, scopes are only checked against the requirements
+// of the scheme that read the claims. Schemes without their own provider, like JWT bearer,
+// and routes without security read them with the route's provider
+func (r RouteDescription) authorizedClaims(src ClaimsSource) (any, error) {
+	if len(r.Security) == 0 {
+		return r.routeClaimsProvider().Claims(src)
+	}
+	read := map[string]any{}
+	var missing []string
+	for _, s := range r.Security {
+		v, ok := read[s.Scheme]
+		if !ok {
+			p := r.schemeClaimsProvider(s.Scheme)
+			if p == nil {
+				p = r.routeClaimsProvider()
+			}
+			var err error
+			if v, err = p.Claims(src); err != nil {
+				return nil, err
+			}
+			read[s.Scheme] = v
+		}
+		if noClaims(v) {
+			continue
+		}
+		m := s.missingScopes(v)
+		if len(m) == 0 {
+			return v, nil
+		}
+		missing = m
+	}
+	if missing == nil {
+		return nil, nil
+	}
+	details := []ErrorDetail{}
+	for _, scope := range missing {
+		details = append(details, Detail("global", "insufficientScope", "missing scope "+scope))
+	}
+	return nil, NewError(http.StatusForbidden, "insufficient scope", details...)
+}
@@ -0,0 +1,28 @@
+//go:build go1.22
+
+package endpoint
+
+import (
+	"net/http"
+)
+
+// StdHTTP adapts an endpoint to the standard library http.ServeMux (Go 1.22+),
+// path parameters are declared with the ServeMux "{param}" syntax and read with r.PathValue
+//
+//	method, pattern, h := endpoint.StdHTTP(endpoint.Get("/api/collection/{id}"), oapi.Route(...), handler)
+//	mux.HandleFunc(method+" "+pattern, h)
+//
+// Modules declaring a go version older than 1.22 route with the old ServeMux unless they set GODEBUG=httpmuxgo121=0
+func StdHTTP[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next Endpoint[C, P, Q, B, D]) (string, string, http.HandlerFunc) {
+	return StdHTTPCtx(p, d, next.withContext())
+}
+
+// StdHTTPCtx is like StdHTTP, the handler receives the request's context
+func StdHTTPCtx[C, P, Q, B any, D dataer](p endpointPath, d OpenAPIRouteDescriber, next EndpointCtx[C, P, Q, B, D]) (string, string, http.HandlerFunc) {
+
+	rdesc := fillOpenAPIRoute[C, P, Q, B, D](p.withPath(serveMuxPathToOpenAPIPath(p.path)), d)
+
+	return string(p.verb), p.path, httpHandler(p, rdesc, next, func(req *http.Request, name string) string {
+		return req.PathValue(name)
+	})
+}
@@ -0,0 +1,54 @@
+//go:build go1.22
+
+// the module supports Go 1.20, ServeMux patterns need the Go 1.22 routing
+//go:debug httpmuxgo121=0
+
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStdHTTP(t *testing.T) {
+	oapi := NewOpenAPI("Endpoint Docs", "v1.0.1")
+	mux := http.NewServeMux()
+
+	method, pattern, h := StdHTTP(
+		Get("/api/endpoint/{id}"),
+		oapi.Route("Get one resource", "description"),
+		func(in EndpointInput[any, struct {
+			ID string `json:"id"`
+		}, struct {
+			Context string `json:"context"`
+		}, any]) (DataResponse[SingleItemData[string]], error) {
+			return DataResponse[SingleItemData[string]]{
+				Context: in.Query.Context,
+				Data: SingleItemData[string]{
+					DataDetail: DataDetail{Kind: "resource"},
+					Item:       in.Params.ID,
+				},
+			}, nil
+		},
+	)
+	mux.HandleFunc(method+" "+pattern, h)
+
+	if oapi.T().Paths["/api/endpoint/{id}"] == nil || oapi.T().Paths["/api/endpoint/{id}"].Get == nil {
+		t.Fatalf("route not documented: %v", oapi.T().Paths)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/endpoint/42?context=ctx", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	expected := []byte(`{"context":"ctx","data":{"kind":"resource","item":"42"}}`)
+	d, err := diffJSON(expected, rec.Body.Bytes())
+	if err != nil {
+		t.Error(err)
+	}
+	if len(d) > 0 {
+		t.Errorf("result not as expected:\n%v", d)
+	}
+}
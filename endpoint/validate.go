@@ -0,0 +1,298 @@
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pindamonhangaba/apiculi/quick_schema"
+	"github.com/pkg/errors"
+)
+
+var (
+	uuidRgx     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	patternRgxs sync.Map
+)
+
+// validateInput checks params, query and body against the constraints declared in their struct tags,
+// each failing field is reported as an ErrorDetail of a 422 Unprocessable Entity error
+func validateInput[C, P, Q, B any](in EndpointInput[C, P, Q, B]) error {
+	var details []ErrorDetail
+	for _, input := range []struct {
+		v            any
+		locationType string
+	}{{in.Params, "path"}, {in.Query, "query"}, {in.Body, "body"}} {
+		d, err := validateValue(reflect.ValueOf(input.v), "", input.locationType)
+		if err != nil {
+			return errors.Wrap(err, "bad validation tags")
+		}
+		details = append(details, d...)
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return NewError(http.StatusUnprocessableEntity, "validation failed", details...)
+}
+
+func validateValue(v reflect.Value, location, locationType string) (details []ErrorDetail, err error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, extra, ok := quick_schema.FieldName(f)
+			if !ok {
+				continue
+			}
+			fv := v.Field(i)
+			if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+				d, err := validateValue(fv, location, locationType)
+				if err != nil {
+					return nil, err
+				}
+				details = append(details, d...)
+				continue
+			}
+			loc := name
+			if len(location) > 0 {
+				loc = location + "." + name
+			}
+			lt := locationType
+			if in := strings.TrimSpace(f.Tag.Get("in")); len(in) > 0 {
+				lt = in
+			}
+			// invalid tags are reported when the route or the oneOf is registered
+			c, err := quick_schema.ParseConstraints(f.Tag)
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %s.%s", t.Name(), f.Name)
+			}
+			c = withTypeEnum(c, f.Type)
+			d, err := checkConstraints(fv, c, has(extra, "omitempty"), loc, lt)
+			if err != nil {
+				return nil, err
+			}
+			if len(d) > 0 {
+				details = append(details, d...)
+				continue
+			}
+			d, err = validateValue(fv, loc, lt)
+			if err != nil {
+				return nil, err
+			}
+			details = append(details, d...)
+		}
+	case reflect.Slice, reflect.Array:
+		if values := quick_schema.EnumValues(indirectType(v.Type().Elem())); len(values) > 0 {
+			c := &quick_schema.Constraints{Enum: values}
+			for i := 0; i < v.Len(); i++ {
+				d, err := checkConstraints(v.Index(i), c, false, location+"["+strconv.Itoa(i)+"]", locationType)
+				if err != nil {
+					return nil, err
+				}
+				details = append(details, d...)
+			}
+			return details, nil
+		}
+		if !hasNestedFields(v.Type().Elem()) {
+			return nil, nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			d, err := validateValue(v.Index(i), location+"["+strconv.Itoa(i)+"]", locationType)
+			if err != nil {
+				return nil, err
+			}
+			details = append(details, d...)
+		}
+	case reflect.Map:
+		if !hasNestedFields(v.Type().Elem()) {
+			return nil, nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			d, err := validateValue(iter.Value(), location+"."+fmt.Sprint(iter.Key().Interface()), locationType)
+			if err != nil {
+				return nil, err
+			}
+			details = append(details, d...)
+		}
+	}
+	return details, nil
+}
+
+func checkConstraints(v reflect.Value, c *quick_schema.Constraints, omitempty bool, location, locationType string) ([]ErrorDetail, error) {
+	if c == nil {
+		return nil, nil
+	}
+	invalid := func(format string, a ...any) ([]ErrorDetail, error) {
+		return []ErrorDetail{
+			Detail("global", "invalid", location+" "+fmt.Sprintf(format, a...)).At(location, locationType),
+		}, nil
+	}
+	isNil := (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil()
+	if c.Required && missing(v) {
+		return []ErrorDetail{
+			Detail("global", "required", location+" is required").At(location, locationType),
+		}, nil
+	}
+	if isNil || (omitempty && v.IsZero()) {
+		return nil, nil
+	}
+	v = reflect.Indirect(v)
+
+	if len(c.Enum) > 0 {
+		s := fmt.Sprint(v.Interface())
+		found := false
+		for _, e := range c.Enum {
+			if fmt.Sprint(e) == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalid("must be one of %v", c.Enum)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		n, _ := strconv.ParseFloat(fmt.Sprint(v.Interface()), 64)
+		if c.Minimum != nil && n < *c.Minimum {
+			return invalid("must be greater than or equal to %v", *c.Minimum)
+		}
+		if c.Maximum != nil && n > *c.Maximum {
+			return invalid("must be less than or equal to %v", *c.Maximum)
+		}
+	case reflect.String:
+		s := v.String()
+		l := uint64(utf8.RuneCountInString(s))
+		if c.MinLength != nil && l < *c.MinLength {
+			return invalid("must be at least %d characters long", *c.MinLength)
+		}
+		if c.MaxLength != nil && l > *c.MaxLength {
+			return invalid("must be at most %d characters long", *c.MaxLength)
+		}
+		if len(c.Pattern) > 0 {
+			r, err := compiledPattern(c.Pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s pattern", location)
+			}
+			if !r.MatchString(s) {
+				return invalid("must match the pattern %s", c.Pattern)
+			}
+		}
+		if !validFormat(c.Format, s) {
+			return invalid("must be a valid %s", c.Format)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		l := uint64(v.Len())
+		if c.MinItems != nil && l < *c.MinItems {
+			return invalid("must have at least %d items", *c.MinItems)
+		}
+		if c.MaxItems != nil && l > *c.MaxItems {
+			return invalid("must have at most %d items", *c.MaxItems)
+		}
+	}
+	return nil, nil
+}
+
+// missing tells if a required value is absent: a nil pointer, interface, slice or map, an empty string,
+// or a zero struct like a File that wasn't uploaded. 0 and false are valid numbers and booleans,
+// make them pointers to require them
+func missing(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return false
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// withTypeEnum adds the values of a field's Enumer type to its constraints, unless its tags declare an enum
+func withTypeEnum(c *quick_schema.Constraints, t reflect.Type) *quick_schema.Constraints {
+	values := quick_schema.EnumValues(indirectType(t))
+	if len(values) == 0 || (c != nil && len(c.Enum) > 0) {
+		return c
+	}
+	withEnum := quick_schema.Constraints{}
+	if c != nil {
+		withEnum = *c
+	}
+	withEnum.Enum = values
+	return &withEnum
+}
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "email":
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	case "uuid":
+		return uuidRgx.MatchString(s)
+	case "uri", "url":
+		u, err := url.ParseRequestURI(s)
+		return err == nil && len(u.Scheme) > 0
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	}
+	return true
+}
+
+func compiledPattern(p string) (*regexp.Regexp, error) {
+	if r, ok := patternRgxs.Load(p); ok {
+		return r.(*regexp.Regexp), nil
+	}
+	r, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	patternRgxs.Store(p, r)
+	return r, nil
+}
+
+// checkConstraintTags panics if the constraints declared in T's tags are invalid,
+// returns whether any were declared
+func checkConstraintTags[T any]() bool {
+	found, err := quick_schema.ConstraintTags(reflect.TypeOf(new(T)).Elem())
+	if err != nil {
+		panic(errors.Wrap(err, "bad validation tags"))
+	}
+	return found
+}
+
+func hasNestedFields(t reflect.Type) bool {
+	switch indirectType(t).Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
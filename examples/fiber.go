@@ -27,7 +27,7 @@ func main() {
 	oapi := endpoint.NewOpenAPI("Endpoint Docs", "v1.0.1")
 	oapi.AddServer(listen, "current server")
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: endpoint.FiberErrorHandler})
 
 	app.Add(endpoint.Fiber(
 		endpoint.Post("/api/endpoint/{id}"),
@@ -50,7 +50,7 @@ func main() {
 		},
 	))
 
-	swagapijson, err := oapi.T().MarshalJSON()
+	swagapijson, err := oapi.MarshalJSON()
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,190 @@
+package quick_schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraints are the validation rules of a node, declared with the "validate", "pattern" and "enum" struct tags,
+// or by types implementing Enumer:
+//
+//	Name  string   `json:"name" validate:"required,minLength=3,maxLength=64" pattern:"^[a-z]+$"`
+//	Email string   `json:"email" validate:"required,format=email"`
+//	Age   int      `json:"age" validate:"min=0,max=150"`
+//	Kind  string   `json:"kind" validate:"enum=person|company"`
+//	Plan  string   `json:"plan" enum:"free,pro,enterprise"`
+//	Tags  []string `json:"tags" validate:"minItems=1,maxItems=10"`
+type Constraints struct {
+	Required  bool
+	Minimum   *float64
+	Maximum   *float64
+	MinLength *uint64
+	MaxLength *uint64
+	Pattern   string
+	Enum      []any
+	// OpenAPI format: email, uuid, uri, date-time...
+	Format   string
+	MinItems *uint64
+	MaxItems *uint64
+}
+
+// ParseConstraints reads the constraints declared in a struct field's tags, nil if there are none
+func ParseConstraints(tag reflect.StructTag) (*Constraints, error) {
+	c := Constraints{
+		Pattern: strings.TrimSpace(tag.Get("pattern")),
+	}
+	rules := strings.TrimSpace(tag.Get("validate"))
+	enum := strings.TrimSpace(tag.Get("enum"))
+	if !val(rules) && !val(c.Pattern) && !val(enum) {
+		return nil, nil
+	}
+	if val(c.Pattern) {
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern \"%s\": %w", c.Pattern, err)
+		}
+	}
+	if val(enum) {
+		for _, e := range strings.Split(enum, ",") {
+			c.Enum = append(c.Enum, strings.TrimSpace(e))
+		}
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if !val(rule) {
+			continue
+		}
+		k, v, _ := strings.Cut(rule, "=")
+		var err error
+		switch k {
+		case "required":
+			c.Required = true
+		case "min":
+			c.Minimum, err = parseFloat(v)
+		case "max":
+			c.Maximum, err = parseFloat(v)
+		case "minLength":
+			c.MinLength, err = parseUint(v)
+		case "maxLength":
+			c.MaxLength, err = parseUint(v)
+		case "minItems":
+			c.MinItems, err = parseUint(v)
+		case "maxItems":
+			c.MaxItems, err = parseUint(v)
+		case "enum":
+			for _, e := range strings.Split(v, "|") {
+				c.Enum = append(c.Enum, e)
+			}
+		case "format":
+			c.Format = v
+		default:
+			err = errors.New("unknown rule")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid validate rule \"%s\": %w", rule, err)
+		}
+	}
+	return &c, nil
+}
+
+// ConstraintTags tells if t's fields, nested types' and oneOf variants' included, declare constraints or have enum types,
+// err is the first invalid constraint declared in their tags
+func ConstraintTags(t reflect.Type) (found bool, err error) {
+	return constraintTags(t, map[reflect.Type]bool{})
+}
+
+func constraintTags(t reflect.Type, seen map[reflect.Type]bool) (found bool, err error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if seen[t] {
+		return false, nil
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return constraintTags(t.Elem(), seen)
+	case reflect.Interface:
+		o, _ := OneOfType(t)
+		for _, vt := range o.Variants {
+			nested, err := constraintTags(vt, seen)
+			if err != nil {
+				return found, err
+			}
+			found = found || nested
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if _, _, ok := FieldName(f); !ok {
+				continue
+			}
+			c, err := ParseConstraints(f.Tag)
+			if err != nil {
+				return found, fmt.Errorf("field %s.%s: %w", t.Name(), f.Name, err)
+			}
+			nested, err := constraintTags(f.Type, seen)
+			if err != nil {
+				return found, err
+			}
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			found = found || c != nil || nested || len(EnumValues(ft)) > 0
+		}
+	}
+	return found, nil
+}
+
+// inherit sets the constraints of a field's type its tags don't override
+func (c *Constraints) inherit(from *Constraints) {
+	if from == nil {
+		return
+	}
+	if c.Minimum == nil {
+		c.Minimum = from.Minimum
+	}
+	if len(c.Enum) == 0 {
+		c.Enum = from.Enum
+	}
+}
+
+// typed converts the enum values declared in tags to the node's JSON type
+func (c *Constraints) typed(format string) {
+	for i, e := range c.Enum {
+		s, ok := e.(string)
+		if !ok {
+			continue
+		}
+		switch format {
+		case "number", "integer":
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				c.Enum[i] = f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(s); err == nil {
+				c.Enum[i] = b
+			}
+		}
+	}
+}
+
+func parseFloat(s string) (*float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func parseUint(s string) (*uint64, error) {
+	u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
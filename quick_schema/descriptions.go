@@ -0,0 +1,45 @@
+package quick_schema
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	descriptionsMu sync.RWMutex
+	// doc comments of types and fields, by "package.Type" and "package.Type.Field"
+	descriptions = map[string]string{}
+)
+
+// RegisterDescriptions describes the types of the package pkg and their fields, by "Type" and "Type.Field".
+// Struct tags' descriptions take precedence, the files cmd/schemadoc generates register the package's doc comments:
+//
+//	//go:generate go run github.com/pindamonhangaba/apiculi/cmd/schemadoc
+func RegisterDescriptions(pkg string, docs map[string]string) {
+	descriptionsMu.Lock()
+	defer descriptionsMu.Unlock()
+	for k, d := range docs {
+		descriptions[pkg+"."+k] = d
+	}
+}
+
+// typeDescription is the registered description of a named type, generic types are described by their declaration
+func typeDescription(t reflect.Type, field string) string {
+	return describedType(t.PkgPath(), t.Name(), field)
+}
+
+// describedType is the registered description of the type typ declared in pkg, or of its field if not empty
+func describedType(pkg, typ, field string) string {
+	name, _, _ := strings.Cut(typ, "[")
+	if len(name) == 0 {
+		return ""
+	}
+	key := pkg + "." + name
+	if len(field) > 0 {
+		key += "." + field
+	}
+	descriptionsMu.RLock()
+	defer descriptionsMu.RUnlock()
+	return descriptions[key]
+}
@@ -0,0 +1,42 @@
+package quick_schema
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Enumer is implemented by types with a fixed set of values, like string or integer constants:
+//
+//	type Status string
+//
+//	const (
+//		StatusActive  Status = "active"
+//		StatusBlocked Status = "blocked"
+//	)
+//
+//	func (Status) Enum() []any { return []any{StatusActive, StatusBlocked} }
+type Enumer interface {
+	Enum() []any
+}
+
+var enumerType = reflect.TypeOf((*Enumer)(nil)).Elem()
+
+// EnumValues are the values of a type implementing Enumer, as they're encoded in JSON,
+// nil for other types and pointers
+func EnumValues(t reflect.Type) []any {
+	if t.Kind() == reflect.Pointer || !t.Implements(enumerType) {
+		return nil
+	}
+	values := []any{}
+	for _, v := range reflect.Zero(t).Interface().(Enumer).Enum() {
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		var e any
+		if err := json.Unmarshal(b, &e); err == nil {
+			values = append(values, e)
+		}
+	}
+	return values
+}
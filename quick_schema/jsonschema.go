@@ -0,0 +1,269 @@
+package quick_schema
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// JSONSchemaDialect is the JSON Schema version of the documents GetJSONSchema builds
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema (draft 2020-12) document or subschema
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Ref         string                 `json:"$ref,omitempty"`
+	Defs        map[string]*JSONSchema `json:"$defs,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	// a type name, or a list of them for nullable types: ["string", "null"]
+	Type                 any                    `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Examples             []any                  `json:"examples,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	PropertyNames        *JSONSchema            `json:"propertyNames,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *uint64                `json:"minLength,omitempty"`
+	MaxLength            *uint64                `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinItems             *uint64                `json:"minItems,omitempty"`
+	MaxItems             *uint64                `json:"maxItems,omitempty"`
+}
+
+// formats JSON Schema and OpenAPI define, other node types are Go type names
+var jsonSchemaFormats = map[string]bool{
+	"date-time": true, "date": true, "time": true, "duration": true,
+	"email": true, "idn-email": true, "hostname": true, "idn-hostname": true, "ipv4": true, "ipv6": true,
+	"uri": true, "uri-reference": true, "iri": true, "iri-reference": true, "uri-template": true, "uuid": true,
+	"json-pointer": true, "relative-json-pointer": true, "regex": true,
+	"int32": true, "int64": true, "float": true, "double": true, "byte": true, "binary": true, "password": true,
+}
+
+// GetJSONSchema is the JSON Schema of T, named structs are defined in $defs and referenced,
+// the document itself references T's definition if T is a named struct:
+//
+//	s, err := quick_schema.GetJSONSchema[Config]()
+//	b, err := json.Marshal(s)
+func GetJSONSchema[T any]() (*JSONSchema, error) {
+	n := GetSchema[T]()
+	if n == nil {
+		return &JSONSchema{Schema: JSONSchemaDialect}, nil
+	}
+	return NewJSONSchema(*n)
+}
+
+// NewJSONSchema is the JSON Schema document of a node, see GetJSONSchema
+func NewJSONSchema(n Node) (*JSONSchema, error) {
+	b := jsonSchemaBuilder{
+		defs:  map[string]*JSONSchema{},
+		types: map[string]Node{},
+	}
+	s := b.schema(n)
+	if b.err != nil {
+		return nil, b.err
+	}
+	s.Schema = JSONSchemaDialect
+	if len(b.defs) > 0 {
+		s.Defs = b.defs
+	}
+	return s, nil
+}
+
+type jsonSchemaBuilder struct {
+	defs map[string]*JSONSchema
+	// Go types of the definitions, by name
+	types map[string]Node
+	err   error
+}
+
+func (b *jsonSchemaBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// schema is the schema of a node, named structs, and other named types nested in themselves, are defined and referenced
+func (b *jsonSchemaBuilder) schema(n Node) *JSONSchema {
+	if len(n.SchemaName) == 0 {
+		return b.inline(n)
+	}
+	// the definition is the type's own, the description, example and constraints of the field using it
+	// are next to the reference
+	typeNode := n
+	typeNode.Description = describedType(n.Package, n.Type, "")
+	typeNode.Example = ""
+	typeNode.Constraints = nil
+	ref := &JSONSchema{
+		Ref: "#/$defs/" + n.SchemaName,
+	}
+	if n.Description != typeNode.Description {
+		ref.Description = n.Description
+	}
+	if len(n.Example) > 0 {
+		ref.Examples = []any{typedValue(n.Format, n.Example)}
+	}
+	applyJSONSchemaConstraints(ref, n.Constraints)
+	if n.Recursive {
+		return ref
+	}
+	t, ok := b.types[n.SchemaName]
+	switch {
+	case !ok:
+		b.types[n.SchemaName] = n
+		// defined first, types nested in themselves reference it
+		def := &JSONSchema{}
+		b.defs[n.SchemaName] = def
+		*def = *b.inline(typeNode)
+	case !t.SameType(n):
+		b.fail(fmt.Errorf("schema name \"%s\" is used by %s.%s and %s.%s, implement SchemaNamer to tell them apart", n.SchemaName, t.Package, t.Type, n.Package, n.Type))
+	}
+	return ref
+}
+
+// inline is the schema of a node, without its definition
+func (b *jsonSchemaBuilder) inline(n Node) *JSONSchema {
+	s := &JSONSchema{
+		Description: n.Description,
+	}
+	if len(n.Example) > 0 {
+		s.Examples = []any{typedValue(n.Format, n.Example)}
+	}
+	switch n.Format {
+	case "object":
+		s.Type = "object"
+		s.Properties = map[string]*JSONSchema{}
+		for _, p := range n.Children {
+			ps := b.property(p)
+			s.Properties[p.Name] = ps
+			required := p.Constraints != nil && p.Constraints.Required
+			if p.Format != "pointer" {
+				required = required || !p.Omitempty
+			}
+			if required {
+				s.Required = append(s.Required, p.Name)
+			}
+		}
+	case "slice", "array":
+		s.Type = "array"
+		if len(n.Children) == 1 {
+			s.Items = b.schema(n.Children[0])
+		}
+	case "map":
+		s.Type = "object"
+		if len(n.Children) == 1 {
+			s.AdditionalProperties = b.schema(n.Children[0])
+		}
+		if len(n.KeyPattern) > 0 {
+			s.PropertyNames = &JSONSchema{Pattern: n.KeyPattern}
+		}
+	case "pointer":
+		if len(n.Children) == 1 {
+			return orNull(b.schema(n.Children[0]))
+		}
+	case "oneOf":
+		for _, c := range n.Children {
+			s.OneOf = append(s.OneOf, b.schema(c))
+		}
+	case "string", "integer", "number", "boolean":
+		s.Type = n.Format
+		if jsonSchemaFormats[n.Type] {
+			s.Format = n.Type
+		}
+	case "complex":
+		b.fail(fmt.Errorf("\"%s\" is a complex number, which can't be encoded as JSON", n.Name))
+	}
+	applyJSONSchemaConstraints(s, n.Constraints)
+	return s
+}
+
+// property is the schema of a struct's property, pointers are nullable and their constraints apply to their values
+func (b *jsonSchemaBuilder) property(p Node) *JSONSchema {
+	if p.Format != "pointer" || len(p.Children) != 1 {
+		return b.schema(p)
+	}
+	v := p.Children[0]
+	if len(v.Description) == 0 {
+		v.Description = p.Description
+	}
+	if len(v.Example) == 0 {
+		v.Example = p.Example
+	}
+	s := b.schema(v)
+	applyJSONSchemaConstraints(s, p.Constraints)
+	return orNull(s)
+}
+
+// orNull adds null to a schema's types, references are wrapped
+func orNull(s *JSONSchema) *JSONSchema {
+	switch t := s.Type.(type) {
+	case string:
+		s.Type = []string{t, "null"}
+		if len(s.Enum) > 0 {
+			s.Enum = append(s.Enum, nil)
+		}
+		return s
+	case nil:
+		if len(s.Ref) == 0 && len(s.OneOf) == 0 {
+			// any value, null included
+			return s
+		}
+	}
+	return &JSONSchema{
+		Description: s.Description,
+		AnyOf:       []*JSONSchema{s, {Type: "null"}},
+	}
+}
+
+func applyJSONSchemaConstraints(s *JSONSchema, c *Constraints) {
+	if c == nil {
+		return
+	}
+	if c.Minimum != nil {
+		s.Minimum = c.Minimum
+	}
+	if c.Maximum != nil {
+		s.Maximum = c.Maximum
+	}
+	if c.MinLength != nil {
+		s.MinLength = c.MinLength
+	}
+	if c.MaxLength != nil {
+		s.MaxLength = c.MaxLength
+	}
+	if len(c.Pattern) > 0 {
+		s.Pattern = c.Pattern
+	}
+	if len(c.Enum) > 0 {
+		s.Enum = c.Enum
+	}
+	if len(c.Format) > 0 {
+		s.Format = c.Format
+	}
+	if c.MinItems != nil {
+		s.MinItems = c.MinItems
+	}
+	if c.MaxItems != nil {
+		s.MaxItems = c.MaxItems
+	}
+}
+
+// typedValue converts a value declared in a tag, like an example, to a node's JSON type
+func typedValue(format, s string) any {
+	switch format {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
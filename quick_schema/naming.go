@@ -0,0 +1,115 @@
+package quick_schema
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// SchemaNamer is implemented by types that name their own schema
+type SchemaNamer interface {
+	SchemaName() string
+}
+
+// TypeName is a readable name of a named type, its generic arguments are appended without their packages,
+// "DataResponse[github.com/org/pkg.SingleItemData[string]]" -> "DataResponseSingleItemDataString".
+// Types implementing SchemaNamer name themselves, though not as generic arguments, unnamed types have no name
+func TypeName(t reflect.Type) string {
+	if len(t.Name()) == 0 {
+		return ""
+	}
+	if n, err := getValueFromStringMethod(t, "SchemaName"); err == nil && len(n) > 0 {
+		return n
+	}
+	base, args, _ := strings.Cut(t.Name(), "[")
+	if len(args) == 0 {
+		return base
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	for _, a := range splitTypeArgs(strings.TrimSuffix(args, "]")) {
+		b.WriteString(readableTypeName(a))
+	}
+	return b.String()
+}
+
+// readableTypeName turns a type's string, as in generic arguments, into an identifier
+func readableTypeName(s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "*"):
+		return readableTypeName(s[1:])
+	case strings.HasPrefix(s, "[]"):
+		return readableTypeName(s[2:]) + "List"
+	case strings.HasPrefix(s, "["):
+		_, elem, _ := strings.Cut(s, "]")
+		return readableTypeName(elem) + "Array"
+	case strings.HasPrefix(s, "map["):
+		k, v := splitMapType(s[len("map["):])
+		return "Map" + readableTypeName(k) + readableTypeName(v)
+	case strings.HasPrefix(s, "struct"):
+		return "Struct"
+	case strings.HasPrefix(s, "interface"):
+		return "Any"
+	case strings.HasPrefix(s, "func"):
+		return "Func"
+	case strings.HasPrefix(s, "chan"):
+		return "Chan"
+	}
+	base, args, _ := strings.Cut(s, "[")
+	if i := strings.LastIndexAny(base, "./"); i >= 0 {
+		base = base[i+1:]
+	}
+	// types declared in functions are suffixed with their index, "B·31"
+	base, _, _ = strings.Cut(base, "·")
+	var b strings.Builder
+	for i, r := range base {
+		if i == 0 {
+			r = unicode.ToUpper(r)
+		}
+		b.WriteRune(r)
+	}
+	if len(args) > 0 {
+		for _, a := range splitTypeArgs(strings.TrimSuffix(args, "]")) {
+			b.WriteString(readableTypeName(a))
+		}
+	}
+	return b.String()
+}
+
+// splitMapType splits "K]V" on the bracket closing the key
+func splitMapType(s string) (string, string) {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return s[:i], s[i+1:]
+			}
+			depth--
+		}
+	}
+	return s, ""
+}
+
+// splitTypeArgs splits generic arguments on top level commas
+func splitTypeArgs(s string) []string {
+	args := []string{}
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, s[start:])
+}
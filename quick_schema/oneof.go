@@ -0,0 +1,165 @@
+package quick_schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// OneOf is an interface whose values are one of a set of variants, told apart by their discriminator property
+type OneOf struct {
+	Discriminator string
+	// types of the variants, by the value of their discriminator property
+	Variants map[string]reflect.Type
+}
+
+var (
+	oneOfsMu sync.RWMutex
+	oneOfs   = map[reflect.Type]OneOf{}
+	// whether types hold registered interfaces, cleared by RegisterOneOf
+	holdsOneOf = map[reflect.Type]bool{}
+)
+
+// RegisterOneOf declares the variants of the interface I, by the value of their discriminator property.
+// Values of I are documented as one of the variants' schemas and decoded into the variant their discriminator names:
+//
+//	quick_schema.RegisterOneOf[Item]("kind", map[string]Item{
+//		"photo": Photo{},
+//		"video": &Video{},
+//	})
+//
+// Panics if I isn't an interface, a variant isn't a struct with the discriminator property or declares invalid constraints
+func RegisterOneOf[I any](discriminator string, variants map[string]I) {
+	t := reflect.TypeOf(new(I)).Elem()
+	if t.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("%s is not an interface", t.String()))
+	}
+	o := OneOf{
+		Discriminator: discriminator,
+		Variants:      map[string]reflect.Type{},
+	}
+	for value, v := range variants {
+		vt := reflect.TypeOf(v)
+		if vt == nil {
+			panic(fmt.Sprintf("nil variant %s of %s", value, t.String()))
+		}
+		st := vt
+		if st.Kind() == reflect.Pointer {
+			st = st.Elem()
+		}
+		if st.Kind() != reflect.Struct || !hasProperty(st, discriminator) {
+			panic(fmt.Sprintf("variant %s of %s is not a struct with a %s property", vt.String(), t.String(), discriminator))
+		}
+		if _, err := ConstraintTags(st); err != nil {
+			panic(fmt.Sprintf("variant %s of %s: %s", vt.String(), t.String(), err))
+		}
+		o.Variants[value] = vt
+	}
+	oneOfsMu.Lock()
+	defer oneOfsMu.Unlock()
+	oneOfs[t] = o
+	holdsOneOf = map[reflect.Type]bool{}
+}
+
+// OneOfType is the registration of the interface t, false if it isn't registered
+func OneOfType(t reflect.Type) (OneOf, bool) {
+	oneOfsMu.RLock()
+	defer oneOfsMu.RUnlock()
+	o, ok := oneOfs[t]
+	return o, ok
+}
+
+// HoldsOneOf tells if values of t hold interfaces registered with RegisterOneOf, in their fields or elements
+func HoldsOneOf(t reflect.Type) bool {
+	oneOfsMu.RLock()
+	found, ok := holdsOneOf[t]
+	oneOfsMu.RUnlock()
+	if ok {
+		return found
+	}
+	oneOfsMu.Lock()
+	defer oneOfsMu.Unlock()
+	found = findOneOf(t, map[reflect.Type]bool{})
+	holdsOneOf[t] = found
+	return found
+}
+
+// findOneOf tells if values of t hold registered interfaces, oneOfsMu is held
+func findOneOf(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		_, ok := oneOfs[t]
+		return ok
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return findOneOf(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if _, _, ok := FieldName(f); ok && findOneOf(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// oneOfNode is the node of a registered interface, its children are its variants named by their discriminator values
+func oneOfNode(t reflect.Type, o OneOf, parents map[reflect.Type]bool) *Node {
+	values := make([]string, 0, len(o.Variants))
+	for v := range o.Variants {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	n := &Node{
+		Type:          t.Name(),
+		Package:       t.PkgPath(),
+		Format:        "oneOf",
+		Children:      []Node{},
+		Discriminator: o.Discriminator,
+	}
+	for _, value := range values {
+		vt := o.Variants[value]
+		if vt.Kind() == reflect.Pointer {
+			vt = vt.Elem()
+		}
+		v := reflect.New(vt).Elem()
+		c := schemaIt(vt, &v, parents)
+		if c == nil {
+			continue
+		}
+		c.Name = value
+		n.Children = append(n.Children, *c)
+	}
+	return n
+}
+
+// hasProperty tells if a struct has a property, embedded structs' included
+func hasProperty(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		n, _, ok := FieldName(f)
+		if !ok {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			if hasProperty(ft, name) {
+				return true
+			}
+			continue
+		}
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
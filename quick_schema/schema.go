@@ -1,9 +1,12 @@
 package quick_schema
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"reflect"
+	"sort"
 	"strings"
 	"unicode"
 )
@@ -13,8 +16,9 @@ type Noder interface {
 }
 
 var (
-	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
-	noderType     = reflect.TypeOf((*Noder)(nil)).Elem()
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	noderType         = reflect.TypeOf((*Noder)(nil)).Elem()
 )
 
 type Node struct {
@@ -26,6 +30,24 @@ type Node struct {
 	Example     string
 	Children    []Node
 	Omitempty   bool
+	Constraints *Constraints `json:",omitempty"`
+	// Where a parameter is read from, declared with the "in" struct tag: "header" or "cookie"
+	In string `json:",omitempty"`
+	// Readable name of a named struct type, see TypeName
+	SchemaName string `json:",omitempty"`
+	// Go type of a named struct, function-local types with the same Package and Type are told apart by it
+	GoType reflect.Type `json:"-"`
+	// Set on a struct nested in itself, its schema is the one of the enclosing node with the same SchemaName
+	Recursive bool `json:",omitempty"`
+	// Pattern of the keys of a map whose keys aren't strings in Go, but are encoded as strings in JSON
+	KeyPattern string `json:",omitempty"`
+	// Property telling apart the Children of a "oneOf" node, their Name is its value, see RegisterOneOf
+	Discriminator string `json:",omitempty"`
+}
+
+// SameType tells if two named struct nodes are of the same Go type, by GoType, and Package and Type for nodes built by hand
+func (n Node) SameType(o Node) bool {
+	return n.GoType == o.GoType && n.Package == o.Package && n.Type == o.Type
 }
 
 func noderEncoder(v reflect.Value) *Node {
@@ -37,120 +59,122 @@ func noderEncoder(v reflect.Value) *Node {
 	return m.Node()
 }
 
+// marshalerEncoder derives a node from the JSON a json.Marshaler encodes its zero value to,
+// zero values encoded as null are made valid and their node is nullable
 func marshalerEncoder(v reflect.Value) (Node, error) {
-	result := ""
+	b, err := marshalJSON(v)
+	if err != nil {
+		return Node{}, err
+	}
+	if string(b) != "null" || v.Kind() != reflect.Struct || !v.CanSet() {
+		n, err := jsonNode(b)
+		n.Package = v.Type().PkgPath()
+		return n, err
+	}
+
+	// nullable types, like null.String
+	if first := v.Field(0); first.CanSet() && (first.Kind() == reflect.Array || first.Kind() == reflect.Slice) {
+		first.Set(reflect.MakeSlice(first.Type(), 1, 1))
+	}
+	if valid := v.FieldByName("Valid"); valid.CanSet() && valid.Kind() == reflect.Bool {
+		valid.SetBool(true)
+	}
+	if b, err = marshalJSON(v); err != nil {
+		return Node{}, err
+	}
+	n, err := jsonNode(b)
+	return Node{
+		Package:  v.Type().PkgPath(),
+		Format:   "pointer",
+		Children: []Node{n},
+	}, err
+}
+
+func marshalJSON(v reflect.Value) ([]byte, error) {
 	if v.Kind() == reflect.Pointer && v.IsNil() {
-		return Node{
-			Type:    v.Type().Name(),
-			Package: v.Type().PkgPath(),
-			Format:  "object",
-		}, errors.New("invalid 1")
+		return nil, errors.New("nil marshaler")
+	}
+	if !v.Type().Implements(marshalerType) && v.CanAddr() {
+		v = v.Addr()
 	}
 	m, ok := v.Interface().(json.Marshaler)
 	if !ok {
-		return Node{
-			Type:    v.Type().Name(),
-			Package: v.Type().PkgPath(),
-			Format:  "object",
-		}, errors.New("invalid 2")
+		return nil, errors.New("not a marshaler")
 	}
-	b, err := m.MarshalJSON()
-	if err != nil {
-		return Node{
-			Type:    v.Type().Name(),
-			Package: v.Type().PkgPath(),
-			Format:  "object",
-		}, errors.New("invalid 3")
-	}
-	result = string(b)
-	isNullable := string(b) == "null"
-	if isNullable && v.Kind() == reflect.Struct {
-		if v.FieldByIndex([]int{0}).CanSet() && (v.FieldByIndex([]int{0}).Kind() == reflect.Array || v.FieldByIndex([]int{0}).Kind() == reflect.Slice) {
-			v.FieldByIndex([]int{0}).Set(reflect.MakeSlice(v.FieldByIndex([]int{0}).Type(), 1, 1))
-		}
-		if v.FieldByName("Valid").CanSet() {
-			v.FieldByName("Valid").Set(reflect.ValueOf(true))
+	return m.MarshalJSON()
+}
+
+// jsonNode is the node of a JSON document
+func jsonNode(b []byte) (Node, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return Node{}, err
+	}
+	return valueNode(v), nil
+}
+
+// valueNode is the node of a value decoded from JSON, arrays are typed by their first item
+func valueNode(v any) Node {
+	switch v := v.(type) {
+	case bool:
+		return Node{Format: "boolean"}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return Node{Type: "int64", Format: "integer"}
 		}
-		m, ok := v.Interface().(json.Marshaler)
-		if !ok {
-			return Node{
-				Type:    v.Type().Name(),
-				Package: v.Type().PkgPath(),
-				Format:  "string",
-			}, errors.New("invalid 4")
+		return Node{Type: "double", Format: "number"}
+	case string:
+		return Node{Format: "string"}
+	case []any:
+		n := Node{Format: "slice"}
+		if len(v) > 0 {
+			n.Children = []Node{valueNode(v[0])}
 		}
-		b, err = m.MarshalJSON()
-		if err != nil {
-			return Node{
-				Type:    v.Type().Name(),
-				Package: v.Type().PkgPath(),
-				Format:  "object",
-			}, errors.New("invalid 5")
+		return n
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
 		}
-		result = string(b)
-	}
-	it := Node{
-		Type:    v.Type().Name(),
-		Package: v.Type().PkgPath(),
-		Format:  "object",
-	}
-	if strings.HasPrefix(result, "\"") && strings.HasSuffix(result, "\"") {
-		it.Format = "string"
-	} else {
-		isNotDigit := func(c rune) bool { return c < '0' || c > '9' }
-		isNumeric := strings.IndexFunc(result, isNotDigit) == -1
-		if isNumeric {
-			it.Format = "number"
-		} else if strings.HasPrefix(result, "[") && strings.HasSuffix(result, "]") {
-			it.Format = "array"
-			if strings.HasPrefix(result, "[true") || strings.HasPrefix(result, "[false") {
-				it.Children = []Node{{
-					Type:   "bolean",
-					Format: "bolean",
-				}}
-			}
-			if strings.HasPrefix(result, "[{") || strings.HasSuffix(result, "}]") {
-				it.Children = []Node{{
-					Type:   "object",
-					Format: "object",
-				}}
-			}
-			if strings.HasPrefix(result, "[\"") || strings.HasSuffix(result, "\"]") {
-				it.Children = []Node{{
-					Type:   "string",
-					Format: "string",
-				}}
-			}
-			if strings.HasPrefix(result, "[[") || strings.HasSuffix(result, "]]") {
-				it.Children = []Node{{
-					Type:   "array",
-					Format: "array",
-				}}
-			}
-			isDigitOrComma := func(c rune) bool { return c >= '0' || c <= '9' || c == ',' }
-			isNumericArr := strings.IndexFunc(result, isDigitOrComma) == -1
-			if isNumericArr {
-				it.Children = []Node{{
-					Type:   "number",
-					Format: "number",
-				}}
-			}
+		sort.Strings(keys)
+		n := Node{Format: "object", Children: []Node{}}
+		for _, k := range keys {
+			c := valueNode(v[k])
+			c.Name = k
+			n.Children = append(n.Children, c)
 		}
+		return n
 	}
-	if isNullable {
-		return Node{
-			Type:     v.Type().Name(),
-			Package:  v.Type().PkgPath(),
-			Format:   "pointer",
-			Children: []Node{it},
-		}, nil
-	}
+	// null, any value
+	return Node{}
+}
 
-	return it, nil
+var containerFormats = map[reflect.Kind]string{
+	reflect.Map:     "map",
+	reflect.Slice:   "slice",
+	reflect.Array:   "array",
+	reflect.Pointer: "pointer",
+}
 
+// nestsItself tells if n holds a reference to the node of t
+func nestsItself(n Node, t reflect.Type) bool {
+	for _, c := range n.Children {
+		if (c.Recursive && c.GoType == t) || nestsItself(c, t) {
+			return true
+		}
+	}
+	return false
 }
 
-func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
+// implements tells if t or, for addressable values, *t implements it
+func implements(t, it reflect.Type) bool {
+	return t.Implements(it) || (t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(it))
+}
+
+// schemaIt builds the node of t, parents are the named types being built by its ancestors
+func schemaIt(t reflect.Type, f *reflect.Value, parents map[reflect.Type]bool) (d *Node) {
 	defer func() {
 		if r := recover(); r != nil {
 			d = &Node{
@@ -165,10 +189,59 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 			return enc
 		}
 	}
+	if n, ok := wellKnownNode(t); ok {
+		return n
+	}
+	if values := EnumValues(t); len(values) > 0 {
+		defer func() {
+			if d != nil {
+				c := &Constraints{Enum: values}
+				c.inherit(d.Constraints)
+				d.Constraints = c
+			}
+		}()
+	}
+	if t.Kind() != reflect.Pointer && implements(t, marshalerType) {
+		if enc, err := marshalerEncoder(*f); err == nil {
+			return &enc
+		}
+	}
+	if t.Kind() != reflect.Pointer && implements(t, textMarshalerType) {
+		return &Node{
+			Package: t.PkgPath(),
+			Format:  "string",
+		}
+	}
 	typ := t.Name()
+	// named maps, slices, arrays and pointers nested in themselves are referenced like structs,
+	// they are named only if they are
+	if format, ok := containerFormats[t.Kind()]; ok && len(typ) > 0 {
+		if parents[t] {
+			return &Node{
+				Type:       typ,
+				Package:    t.PkgPath(),
+				Format:     format,
+				SchemaName: TypeName(t),
+				GoType:     t,
+				Recursive:  true,
+			}
+		}
+		parents[t] = true
+		defer delete(parents, t)
+		defer func() {
+			if d != nil && nestsItself(*d, t) {
+				d.SchemaName, d.GoType = TypeName(t), t
+			}
+		}()
+	}
 	switch t.Kind() {
+	case reflect.Interface:
+		if o, ok := OneOfType(t); ok {
+			return oneOfNode(t, o, parents)
+		}
 	case reflect.Map:
-		if t.Key().Name() != reflect.String.String() {
+		pattern, ok := mapKeyPattern(t.Key())
+		if !ok {
 			return &Node{
 				Type:   "non-string-keys-map",
 				Format: "object",
@@ -177,15 +250,16 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 		fv := reflect.New(t.Elem())
 		e := fv.Elem()
 		items := []Node{}
-		itm := schemaIt(t.Elem(), &e)
+		itm := schemaIt(t.Elem(), &e, parents)
 		if itm != nil {
 			items = append(items, *itm)
 		}
 		return &Node{
-			Type:     typ,
-			Package:  t.PkgPath(),
-			Format:   "map",
-			Children: items,
+			Type:       typ,
+			Package:    t.PkgPath(),
+			Format:     "map",
+			Children:   items,
+			KeyPattern: pattern,
 		}
 	case reflect.Slice:
 		s1 := reflect.SliceOf(t)
@@ -194,7 +268,7 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 		e := fv.Elem()
 
 		items := []Node{}
-		itm := schemaIt(tt, &e)
+		itm := schemaIt(tt, &e, parents)
 		if itm != nil {
 			items = append(items, *itm)
 		}
@@ -220,7 +294,7 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 		}
 
 		items := []Node{}
-		itm := schemaIt(tt, &e)
+		itm := schemaIt(tt, &e, parents)
 		if itm != nil {
 			items = append(items, *itm)
 		}
@@ -234,7 +308,7 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 		fv := reflect.New(t.Elem())
 		e := fv.Elem()
 		items := []Node{}
-		itm := schemaIt(e.Type(), &e)
+		itm := schemaIt(e.Type(), &e, parents)
 		if itm != nil {
 			items = append(items, *itm)
 		}
@@ -257,34 +331,29 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 			Format:  "boolean",
 		}
 	case reflect.Struct:
-		if t.Implements(marshalerType) {
-			enc, err := marshalerEncoder(*f)
-			if err == nil {
-				return &enc
+		if parents[t] {
+			return &Node{
+				Type:       typ,
+				Package:    t.PkgPath(),
+				Format:     "object",
+				SchemaName: TypeName(t),
+				GoType:     t,
+				Recursive:  true,
 			}
 		}
+		parents[t] = true
+		defer delete(parents, t)
+
 		items := []Node{}
 		for i := 0; i < f.NumField(); i++ {
 			v := f.Field(i)
 			vv := t.Field(i)
-			if !vv.IsExported() {
+			name, extra, ok := FieldName(vv)
+			if !ok {
 				continue
 			}
-			jsontag := strings.TrimSpace(vv.Tag.Get("json"))
-			if jsontag == "-" {
-				continue
-			}
-			name := vv.Name
-			nmeth, err := getValueFromStringMethod(vv.Type, "Name")
-			if err == nil && isValidTag(nmeth) {
-				name = nmeth
-			}
-			n, extra := parseTag(jsontag)
-			if isValidTag(n) {
-				name = n
-			}
 
-			itm := schemaIt(vv.Type, &v)
+			itm := schemaIt(vv.Type, &v, parents)
 			itm.Omitempty = contains("omitempty", extra)
 			if vv.Anonymous && vv.Type.Kind() == reflect.Slice && f.NumField() == 1 {
 				return itm
@@ -297,6 +366,9 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 						itm.Name = name
 					}
 					d := strings.TrimSpace(vv.Tag.Get("description"))
+					if !val(d) {
+						d = typeDescription(t, vv.Name)
+					}
 					if val(d) {
 						itm.Description = d
 					}
@@ -311,6 +383,13 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 							itm.Format = ""
 						}
 					}
+					itm.In = strings.TrimSpace(vv.Tag.Get("in"))
+					cons, err := ParseConstraints(vv.Tag)
+					if err == nil && cons != nil {
+						cons.typed(itm.Format)
+						cons.inherit(itm.Constraints)
+						itm.Constraints = cons
+					}
 					typetag := strings.TrimSpace(vv.Tag.Get("type"))
 					typt, _ := parseTag(typetag)
 					if isValidTag(typt) {
@@ -326,10 +405,13 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 
 		}
 		return &Node{
-			Type:     typ,
-			Package:  t.PkgPath(),
-			Format:   "object",
-			Children: items,
+			Type:        typ,
+			Package:     t.PkgPath(),
+			Format:      "object",
+			Description: typeDescription(t, ""),
+			Children:    items,
+			SchemaName:  TypeName(t),
+			GoType:      t,
 		}
 	case reflect.Int,
 		reflect.Int8,
@@ -343,19 +425,60 @@ func schemaIt(t reflect.Type, f *reflect.Value) (d *Node) {
 		reflect.Uint64,
 		reflect.Uintptr,
 		reflect.Float32,
-		reflect.Float64,
-		reflect.Complex64,
+		reflect.Float64:
+		return numberNode(t)
+	case reflect.Complex64,
 		reflect.Complex128:
+		// encoding/json can't encode complex numbers
 		return &Node{
 			Type:    t.Name(),
 			Package: t.PkgPath(),
-			Format:  "number",
+			Format:  "complex",
 		}
 	default:
 	}
 	return d
 }
 
+// numberNode is the node of a number kind, its Type is the OpenAPI format of the kind
+// and unsigned kinds have a minimum of 0
+func numberNode(t reflect.Type) *Node {
+	n := &Node{
+		Type:    "int64",
+		Package: t.PkgPath(),
+		Format:  "integer",
+	}
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		n.Type = "int32"
+	case reflect.Float32:
+		n.Type, n.Format = "float", "number"
+	case reflect.Float64:
+		n.Type, n.Format = "double", "number"
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		min := 0.0
+		n.Constraints = &Constraints{Minimum: &min}
+	}
+	return n
+}
+
+// mapKeyPattern is the pattern of a map's keys as encoded by encoding/json,
+// false if encoding/json can't encode the key type
+func mapKeyPattern(k reflect.Type) (string, bool) {
+	if k.Kind() == reflect.String || k.Implements(textMarshalerType) {
+		return "", true
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "^-?[0-9]+$", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "^[0-9]+$", true
+	}
+	return "", false
+}
+
 func GetSchema[T any]() *Node {
 	t := *new(T)
 	tt := reflect.TypeOf(t)
@@ -364,10 +487,32 @@ func GetSchema[T any]() *Node {
 	}
 	ptr := reflect.New(tt)
 	e := ptr.Elem()
-	its := schemaIt(tt, &e)
+	its := schemaIt(tt, &e, map[reflect.Type]bool{})
 	return its
 }
 
+// FieldName returns the name of a struct field in the schema and its json tag options,
+// false if the field is not part of the schema
+func FieldName(f reflect.StructField) (string, []string, bool) {
+	if !f.IsExported() {
+		return "", nil, false
+	}
+	jsontag := strings.TrimSpace(f.Tag.Get("json"))
+	if jsontag == "-" {
+		return "", nil, false
+	}
+	name := f.Name
+	nmeth, err := getValueFromStringMethod(f.Type, "Name")
+	if err == nil && isValidTag(nmeth) {
+		name = nmeth
+	}
+	n, extra := parseTag(jsontag)
+	if isValidTag(n) {
+		name = n
+	}
+	return name, extra, true
+}
+
 func parseTag(tag string) (string, []string) {
 	tag, opt, _ := strings.Cut(tag, ",")
 	return tag, strings.Split(opt, ",")
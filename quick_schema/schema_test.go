@@ -2,6 +2,7 @@ package quick_schema
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 
@@ -58,7 +59,7 @@ func diffJSON(a, b []byte) (string, error) {
 }
 
 func TestGetSchema(t *testing.T) {
-	expected := `{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"DataResponse[github.com/pindamonhangaba/apiculi/quick_schema.resp]","Format":"object","Name":"","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"context","Description":"","Example":"","Children":null},{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"resp","Format":"object","Name":"data","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"a","Description":"stuff aa","Example":"here we go, travelling with jesus","Children":null},{"Package":"","Type":"string","Format":"string","Name":"b","Description":"","Example":"","Children":null}]}]}`
+	expected := `{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"DataResponse[github.com/pindamonhangaba/apiculi/quick_schema.resp]","Format":"object","Name":"","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"context","Description":"","Example":"","Children":null,"Omitempty":true},{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"resp","Format":"object","Name":"data","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"a","Description":"stuff aa","Example":"here we go, travelling with jesus","Children":null,"Omitempty":false},{"Package":"","Type":"string","Format":"string","Name":"b","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false,"SchemaName":"resp"}],"Omitempty":false,"SchemaName":"DataResponseResp"}`
 	schema := GetSchema[DataResponse[resp]]()
 	j, err := json.Marshal(schema)
 	if err != nil {
@@ -75,7 +76,7 @@ func TestGetSchema(t *testing.T) {
 }
 
 func TestEmbededTypes(t *testing.T) {
-	expected := `{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"B","Format":"object","Name":"","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"FirstMe","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"","Type":"int64","Format":"number","Name":"NUmberSutff","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"","Type":"int32","Format":"number","Name":"FirstMe","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"time","Type":"Time","Format":"string","Name":"NOthingHere","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false}`
+	expected := `{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"B","Format":"object","Name":"","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"FirstMe","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"","Type":"int64","Format":"integer","Name":"NUmberSutff","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"","Type":"int32","Format":"integer","Name":"FirstMe","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"time","Type":"date-time","Format":"string","Name":"NOthingHere","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false,"SchemaName":"B"}`
 	type EMbedMe struct {
 		FirstMe     string
 		NUmberSutff int64
@@ -106,7 +107,7 @@ type PushTokens struct {
 
 func TestComplexTypes(t *testing.T) {
 
-	expected := `{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"B","Format":"object","Name":"","Description":"","Example":"","Children":[{"Package":"github.com/gofrs/uuid","Type":"string","Format":"string","Name":"acveID","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"github.com/gofrs/uuid","Type":"UUID","Format":"string","Name":"ID","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"time","Type":"Time","Format":"string","Name":"createdAt","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"","Type":"string","Format":"string","Name":"opt","Description":"","Example":"","Children":null,"Omitempty":true},{"Package":"github.com/lib/pq","Type":"StringArray","Format":"slice","Name":"PushTokens","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false},{"Package":"","Type":"","Format":"slice","Name":"PushTokens2","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false},{"Package":"","Type":"","Format":"slice","Name":"PushTokens3","Description":"","Example":"","Children":[{"Package":"","Type":"uint8","Format":"number","Name":"","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false}],"Omitempty":false}`
+	expected := `{"Package":"github.com/pindamonhangaba/apiculi/quick_schema","Type":"B","Format":"object","Name":"","Description":"","Example":"","Children":[{"Package":"github.com/gofrs/uuid","Type":"string","Format":"string","Name":"acveID","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"github.com/gofrs/uuid","Type":"uuid","Format":"string","Name":"ID","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"time","Type":"date-time","Format":"string","Name":"createdAt","Description":"","Example":"","Children":null,"Omitempty":false},{"Package":"","Type":"string","Format":"string","Name":"opt","Description":"","Example":"","Children":null,"Omitempty":true},{"Package":"github.com/lib/pq","Type":"","Format":"slice","Name":"PushTokens","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false},{"Package":"","Type":"","Format":"slice","Name":"PushTokens2","Description":"","Example":"","Children":[{"Package":"","Type":"string","Format":"string","Name":"","Description":"","Example":"","Children":null,"Omitempty":false}],"Omitempty":false},{"Package":"","Type":"","Format":"slice","Name":"PushTokens3","Description":"","Example":"","Children":[{"Package":"","Type":"int32","Format":"integer","Name":"","Description":"","Example":"","Children":null,"Omitempty":false,"Constraints":{"Required":false,"Minimum":0,"Maximum":null,"MinLength":null,"MaxLength":null,"Pattern":"","Enum":null,"Format":"","MinItems":null,"MaxItems":null}}],"Omitempty":false}],"Omitempty":false,"SchemaName":"B"}`
 
 	type B struct {
 		AcveID      uuid.UUID `db:"acve_id" json:"acveID" type:"string"`
@@ -131,3 +132,504 @@ func TestComplexTypes(t *testing.T) {
 		t.Errorf("result not as expected:\n%v", d)
 	}
 }
+
+type named struct{}
+
+func (named) SchemaName() string { return "CustomName" }
+
+type pair[K, V any] struct{}
+
+func TestTypeName(t *testing.T) {
+	type local struct{}
+	cases := []struct {
+		got, expected string
+	}{
+		{TypeName(reflect.TypeOf(resp{})), "resp"},
+		{TypeName(reflect.TypeOf(DataResponse[resp]{})), "DataResponseResp"},
+		{TypeName(reflect.TypeOf(pair[resp, DataResponse[resp]]{})), "pairRespDataResponseResp"},
+		{TypeName(reflect.TypeOf(pair[*time.Time, []uuid.UUID]{})), "pairTimeUUIDList"},
+		{TypeName(reflect.TypeOf(pair[map[string]int, [2]pq.StringArray]{})), "pairMapStringIntStringArrayArray"},
+		{TypeName(reflect.TypeOf(pair[local, *local]{})), "pairLocalLocal"},
+		{TypeName(reflect.TypeOf(named{})), "CustomName"},
+		{TypeName(reflect.TypeOf(struct{}{})), ""},
+	}
+	for _, c := range cases {
+		if c.got != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, c.got)
+		}
+	}
+}
+
+type category struct {
+	Name     string     `json:"name"`
+	Children []category `json:"children"`
+}
+
+type comment struct {
+	Text    string   `json:"text"`
+	Parent  *comment `json:"parent,omitempty"`
+	Replies []thread `json:"replies"`
+}
+
+type thread struct {
+	Comments []*comment `json:"comments"`
+}
+
+type objTree map[string]objTree
+
+type listTree []listTree
+
+func TestRecursiveTypes(t *testing.T) {
+	n := GetSchema[category]()
+	items := n.Children[1].Children
+	if len(items) != 1 || !items[0].Recursive || items[0].SchemaName != "category" || len(items[0].Children) > 0 {
+		t.Errorf("expected a recursive category item, got %+v", items)
+	}
+
+	n = GetSchema[comment]()
+	if n.Recursive {
+		t.Error("root marked as recursive")
+	}
+	parent := n.Children[1].Children[0]
+	if !parent.Recursive || parent.SchemaName != "comment" {
+		t.Errorf("expected a recursive parent, got %+v", parent)
+	}
+	replies := n.Children[2].Children[0]
+	if replies.Recursive || replies.SchemaName != "thread" {
+		t.Errorf("expected thread replies, got %+v", replies)
+	}
+	if c := replies.Children[0].Children[0].Children[0]; !c.Recursive || c.SchemaName != "comment" {
+		t.Errorf("expected recursive comments in thread, got %+v", c)
+	}
+
+	// siblings of the same type are not recursive
+	type pair struct {
+		A category
+		B category
+	}
+	n = GetSchema[pair]()
+	if n.Children[0].Recursive || n.Children[1].Recursive {
+		t.Error("siblings marked as recursive")
+	}
+	// named maps and slices nested in themselves are referenced like structs
+	type trees struct {
+		Obj  objTree  `json:"obj"`
+		List listTree `json:"list"`
+		Tags []string `json:"tags"`
+	}
+	n = GetSchema[trees]()
+	for i, name := range []string{"objTree", "listTree"} {
+		c := n.Children[i]
+		if c.SchemaName != name || c.Recursive || len(c.Children) != 1 || !c.Children[0].Recursive || c.Children[0].SchemaName != name {
+			t.Errorf("expected a recursive %s, got %+v", name, c)
+		}
+	}
+	if len(n.Children[2].SchemaName) > 0 {
+		t.Errorf("unexpected schema name for tags: %s", n.Children[2].SchemaName)
+	}
+	s, err := GetJSONSchema[trees]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(s.Defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"listTree":{"type":"array","items":{"$ref":"#/$defs/listTree"}},"objTree":{"type":"object","additionalProperties":{"$ref":"#/$defs/objTree"}},"trees":{"type":"object","properties":{"list":{"$ref":"#/$defs/listTree"},"obj":{"$ref":"#/$defs/objTree"},"tags":{"type":"array","items":{"type":"string"}}},"required":["obj","list","tags"]}}`
+	d, err := diffJSON([]byte(expected), b)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(d) > 0 {
+		t.Errorf("result not as expected:\n%v", d)
+	}
+}
+
+func TestNumberFormats(t *testing.T) {
+	type id uint64
+	type B struct {
+		Int     int
+		Int8    int8
+		Int32   int32
+		Uint16  uint16
+		Uint32  uint32
+		ID      id
+		Float32 float32
+		Float64 float64
+		Bounded uint `validate:"max=10"`
+		Signed  uint `validate:"min=-1"`
+	}
+	expected := []struct {
+		format, typ string
+		min         *float64
+	}{
+		{"integer", "int64", nil},
+		{"integer", "int32", nil},
+		{"integer", "int32", nil},
+		{"integer", "int32", ptr(0.0)},
+		{"integer", "int64", ptr(0.0)},
+		{"integer", "int64", ptr(0.0)},
+		{"number", "float", nil},
+		{"number", "double", nil},
+		{"integer", "int64", ptr(0.0)},
+		{"integer", "int64", ptr(-1.0)},
+	}
+	n := GetSchema[B]()
+	for i, c := range n.Children {
+		e := expected[i]
+		if c.Format != e.format || c.Type != e.typ {
+			t.Errorf("%s: expected %s/%s, got %s/%s", c.Name, e.format, e.typ, c.Format, c.Type)
+		}
+		var min *float64
+		if c.Constraints != nil {
+			min = c.Constraints.Minimum
+		}
+		if (min == nil) != (e.min == nil) || (min != nil && *min != *e.min) {
+			t.Errorf("%s: expected minimum %v, got %v", c.Name, e.min, min)
+		}
+	}
+	if c := n.Children[8].Constraints; c.Maximum == nil || *c.Maximum != 10 {
+		t.Errorf("Bounded: expected maximum 10, got %v", c.Maximum)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type money struct {
+	cents int64
+}
+
+func TestWellKnownTypes(t *testing.T) {
+	Register[money](Node{Type: "decimal", Format: "string"})
+	type B struct {
+		At       time.Time       `json:"at"`
+		Timeout  time.Duration   `json:"timeout"`
+		ID       uuid.UUID       `json:"id"`
+		Name     null.String     `json:"name"`
+		Count    null.Int        `json:"count"`
+		Seen     null.Time       `json:"seen"`
+		Tags     pq.StringArray  `json:"tags"`
+		Raw      json.RawMessage `json:"raw"`
+		Price    money           `json:"price"`
+		Discount *money          `json:"discount"`
+	}
+	expected := []struct {
+		format, typ string
+	}{
+		{"string", "date-time"},
+		{"integer", "int64"},
+		{"string", "uuid"},
+		{"pointer", "string"},
+		{"pointer", "int64"},
+		{"pointer", "date-time"},
+		{"slice", "string"},
+		{"", ""},
+		{"string", "decimal"},
+		{"pointer", "decimal"},
+	}
+	n := GetSchema[B]()
+	for i, c := range n.Children {
+		e := expected[i]
+		typ := c.Type
+		if len(c.Children) == 1 {
+			typ = c.Children[0].Type
+		}
+		if c.Format != e.format || typ != e.typ {
+			t.Errorf("%s: expected %s/%s, got %s/%s", c.Name, e.format, e.typ, c.Format, typ)
+		}
+	}
+}
+
+type enabled string
+
+func (enabled) MarshalJSON() ([]byte, error) {
+	return []byte("true"), nil
+}
+
+type temperature struct{}
+
+func (temperature) MarshalJSON() ([]byte, error) {
+	return []byte(`-12.5`), nil
+}
+
+type point struct{}
+
+func (point) MarshalJSON() ([]byte, error) {
+	return []byte(`{"x":1,"y":-2,"tags":["a"],"meta":{"ok":true}}`), nil
+}
+
+type readings struct{}
+
+func (*readings) MarshalJSON() ([]byte, error) {
+	return []byte(`[1,2,3]`), nil
+}
+
+type level int
+
+func (level) MarshalText() ([]byte, error) {
+	return []byte("info"), nil
+}
+
+type optional struct {
+	Value string
+	Valid bool
+}
+
+func (o optional) MarshalJSON() ([]byte, error) {
+	if !o.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+func TestMarshalerTypes(t *testing.T) {
+	type B struct {
+		Flag        enabled     `json:"flag"`
+		Temperature temperature `json:"temperature"`
+		Point       point       `json:"point"`
+		Readings    readings    `json:"readings"`
+		Level       level       `json:"level"`
+		Optional    optional    `json:"optional"`
+	}
+	n := GetSchema[B]()
+
+	if c := n.Children[0]; c.Format != "boolean" {
+		t.Errorf("flag: expected boolean, got %s", c.Format)
+	}
+	if c := n.Children[1]; c.Format != "number" || c.Type != "double" {
+		t.Errorf("temperature: expected number/double, got %s/%s", c.Format, c.Type)
+	}
+	point := n.Children[2]
+	if point.Format != "object" || len(point.Children) != 4 {
+		t.Fatalf("point: expected an object with 4 properties, got %+v", point)
+	}
+	expected := map[string]string{"x": "integer", "y": "integer", "tags": "slice", "meta": "object"}
+	for _, c := range point.Children {
+		if c.Format != expected[c.Name] {
+			t.Errorf("point.%s: expected %s, got %s", c.Name, expected[c.Name], c.Format)
+		}
+	}
+	if c := point.Children[0]; c.Name != "meta" || c.Children[0].Format != "boolean" {
+		t.Errorf("point.meta: expected a boolean ok, got %+v", c)
+	}
+	if c := n.Children[3]; c.Format != "slice" || len(c.Children) != 1 || c.Children[0].Format != "integer" {
+		t.Errorf("readings: expected an integer array, got %+v", c)
+	}
+	if c := n.Children[4]; c.Format != "string" {
+		t.Errorf("level: expected string, got %s", c.Format)
+	}
+	if c := n.Children[5]; c.Format != "pointer" || len(c.Children) != 1 || c.Children[0].Format != "string" {
+		t.Errorf("optional: expected a nullable string, got %+v", c)
+	}
+}
+
+type shape interface {
+	area() float64
+}
+
+type circle struct {
+	Kind   string  `json:"kind"`
+	Radius float64 `json:"radius"`
+}
+
+func (circle) area() float64 { return 0 }
+
+type square struct {
+	Side float64 `json:"side"`
+}
+
+func (square) area() float64 { return 0 }
+
+type ellipse struct {
+	Kind  string `json:"kind"`
+	Label string `json:"label" pattern:"[a-"`
+}
+
+func (ellipse) area() float64 { return 0 }
+
+func TestOneOf(t *testing.T) {
+	RegisterOneOf[shape]("kind", map[string]shape{"circle": circle{}})
+	type B struct {
+		Shape shape `json:"shape"`
+	}
+	n := GetSchema[B]().Children[0]
+	if n.Format != "oneOf" || n.Discriminator != "kind" || len(n.Children) != 1 || n.Children[0].Name != "circle" || n.Children[0].SchemaName != "circle" {
+		t.Errorf("expected a oneOf of circle, got %+v", n)
+	}
+
+	panics := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s accepted", name)
+			}
+		}()
+		f()
+	}
+	panics("variant without discriminator", func() {
+		RegisterOneOf[shape]("kind", map[string]shape{"square": square{}})
+	})
+	panics("variant with an invalid pattern", func() {
+		RegisterOneOf[shape]("kind", map[string]shape{"ellipse": ellipse{}})
+	})
+	panics("concrete type", func() {
+		RegisterOneOf[circle]("kind", map[string]circle{"circle": {}})
+	})
+}
+
+type vehicle interface {
+	wheels() int
+}
+
+type bike struct {
+	Kind  string `json:"kind"`
+	Gears int    `json:"gears" validate:"min=1"`
+}
+
+func (bike) wheels() int { return 2 }
+
+type grade string
+
+func (grade) Enum() []any { return []any{grade("low"), grade("high")} }
+
+func TestConstraintTags(t *testing.T) {
+	RegisterOneOf[vehicle]("kind", map[string]vehicle{"bike": bike{}})
+	type plain struct {
+		Name string `json:"name"`
+	}
+	type tagged struct {
+		Items []struct {
+			Name string `json:"name" validate:"required"`
+		} `json:"items"`
+	}
+	type enum struct {
+		Grade *grade `json:"grade"`
+	}
+	type variants struct {
+		Vehicle vehicle `json:"vehicle"`
+	}
+	type invalid struct {
+		Name string `json:"name" pattern:"[a-"`
+	}
+	for name, tc := range map[string]struct {
+		t     reflect.Type
+		found bool
+	}{
+		"plain":    {reflect.TypeOf(plain{}), false},
+		"nested":   {reflect.TypeOf(&tagged{}), true},
+		"enum":     {reflect.TypeOf(enum{}), true},
+		"variants": {reflect.TypeOf(variants{}), true},
+	} {
+		found, err := ConstraintTags(tc.t)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if found != tc.found {
+			t.Errorf("%s: expected found %v, got %v", name, tc.found, found)
+		}
+	}
+	if _, err := ConstraintTags(reflect.TypeOf(invalid{})); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+type invoiceLine struct {
+	SKU   string  `json:"sku"`
+	Price float64 `json:"price" description:"Unit price"`
+}
+
+func TestDescriptions(t *testing.T) {
+	RegisterDescriptions("github.com/pindamonhangaba/apiculi/quick_schema", map[string]string{
+		"invoiceLine":       "A line of an invoice",
+		"invoiceLine.SKU":   "Stock keeping unit",
+		"invoiceLine.Price": "Price in cents",
+		"DataResponse.Data": "Response payload",
+	})
+	n := GetSchema[invoiceLine]()
+	if n.Description != "A line of an invoice" {
+		t.Errorf("unexpected type description %q", n.Description)
+	}
+	if d := n.Children[0].Description; d != "Stock keeping unit" {
+		t.Errorf("unexpected sku description %q", d)
+	}
+	if d := n.Children[1].Description; d != "Unit price" {
+		t.Errorf("tag description overridden by %q", d)
+	}
+	if d := GetSchema[DataResponse[resp]]().Children[1].Description; d != "Response payload" {
+		t.Errorf("unexpected generic field description %q", d)
+	}
+}
+
+type jsonSchemaServer struct {
+	Host string `json:"host" validate:"format=hostname"`
+	Port uint16 `json:"port" validate:"max=65535"`
+}
+
+type jsonSchemaConfig struct {
+	Name    string                      `json:"name" validate:"minLength=3" example:"api"`
+	Mode    string                      `json:"mode,omitempty" enum:"dev,prod"`
+	Server  jsonSchemaServer            `json:"server" description:"Main server"`
+	Backup  *jsonSchemaServer           `json:"backup"`
+	Timeout *int                        `json:"timeout" validate:"min=1"`
+	Limits  map[int]float64             `json:"limits,omitempty"`
+	Tags    []string                    `json:"tags" validate:"maxItems=5"`
+	Replica map[string]jsonSchemaServer `json:"replica"`
+	Tree    category                    `json:"tree"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	// the type's description is in its definition, the field's next to its reference
+	RegisterDescriptions("github.com/pindamonhangaba/apiculi/quick_schema", map[string]string{
+		"jsonSchemaServer": "A server to connect to",
+	})
+	s, err := GetJSONSchema[jsonSchemaConfig]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/jsonSchemaConfig","$defs":{"category":{"type":"object","properties":{"children":{"type":"array","items":{"$ref":"#/$defs/category"}},"name":{"type":"string"}},"required":["name","children"]},"jsonSchemaConfig":{"type":"object","properties":{"backup":{"anyOf":[{"$ref":"#/$defs/jsonSchemaServer"},{"type":"null"}]},"limits":{"type":"object","additionalProperties":{"type":"number","format":"double"},"propertyNames":{"pattern":"^-?[0-9]+$"}},"mode":{"type":"string","enum":["dev","prod"]},"name":{"type":"string","examples":["api"],"minLength":3},"replica":{"type":"object","additionalProperties":{"$ref":"#/$defs/jsonSchemaServer"}},"server":{"$ref":"#/$defs/jsonSchemaServer","description":"Main server"},"tags":{"type":"array","items":{"type":"string"},"maxItems":5},"timeout":{"type":["integer","null"],"format":"int64","minimum":1},"tree":{"$ref":"#/$defs/category"}},"required":["name","server","tags","replica","tree"]},"jsonSchemaServer":{"description":"A server to connect to","type":"object","properties":{"host":{"type":"string","format":"hostname"},"port":{"type":"integer","format":"int32","minimum":0,"maximum":65535}},"required":["host","port"]}}}`
+	d, err := diffJSON([]byte(expected), b)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(d) > 0 {
+		t.Errorf("result not as expected:\n%v", d)
+	}
+
+	type C struct {
+		Z complex128 `json:"z"`
+	}
+	if _, err := GetJSONSchema[C](); err == nil {
+		t.Error("complex number accepted")
+	}
+
+	user := func(pkg string) Node {
+		return Node{Package: pkg, Type: "User", Format: "object", SchemaName: "User"}
+	}
+	_, err = NewJSONSchema(Node{Format: "object", Children: []Node{user("a"), user("b")}})
+	if err == nil {
+		t.Error("schema name collision accepted")
+	}
+
+	// function-local types with the same name only differ by their reflect.Type
+	type item struct {
+		A int `json:"a"`
+	}
+	type firstItem = item
+	{
+		type item struct {
+			B int `json:"b"`
+		}
+		type both struct {
+			First  firstItem `json:"first"`
+			Second item      `json:"second"`
+		}
+		if _, err := GetJSONSchema[both](); err == nil {
+			t.Error("local types name collision accepted")
+		}
+	}
+}
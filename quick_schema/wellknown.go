@@ -0,0 +1,78 @@
+package quick_schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	wellKnownMu sync.RWMutex
+	// nodes of types whose JSON isn't their Go structure, by typeKey
+	wellKnown = map[string]Node{
+		"github.com/gofrs/uuid.UUID":     {Type: "uuid", Format: "string"},
+		"github.com/google/uuid.UUID":    {Type: "uuid", Format: "string"},
+		"github.com/lib/pq.StringArray":  arrayOf(Node{Type: "string", Format: "string"}),
+		"github.com/lib/pq.Int64Array":   arrayOf(Node{Type: "int64", Format: "integer"}),
+		"github.com/lib/pq.Int32Array":   arrayOf(Node{Type: "int32", Format: "integer"}),
+		"github.com/lib/pq.Float64Array": arrayOf(Node{Type: "double", Format: "number"}),
+		"github.com/lib/pq.Float32Array": arrayOf(Node{Type: "float", Format: "number"}),
+		"github.com/lib/pq.BoolArray":    arrayOf(Node{Format: "boolean"}),
+		"github.com/lib/pq.ByteaArray":   arrayOf(Node{Type: "byte", Format: "string"}),
+	}
+)
+
+func init() {
+	// standard library types may be aliases, their keys are those of the aliased types
+	wellKnown[typeKey(reflect.TypeOf(time.Time{}))] = Node{Type: "date-time", Format: "string"}
+	wellKnown[typeKey(reflect.TypeOf(time.Duration(0)))] = Node{Type: "int64", Format: "integer", Description: "duration in nanoseconds"}
+	wellKnown[typeKey(reflect.TypeOf(json.RawMessage(nil)))] = Node{}
+	for _, pkg := range []string{"gopkg.in/guregu/null.v3", "gopkg.in/guregu/null.v4"} {
+		wellKnown[pkg+".String"] = nullable(Node{Type: "string", Format: "string"})
+		wellKnown[pkg+".Int"] = nullable(Node{Type: "int64", Format: "integer"})
+		wellKnown[pkg+".Float"] = nullable(Node{Type: "double", Format: "number"})
+		wellKnown[pkg+".Bool"] = nullable(Node{Format: "boolean"})
+		wellKnown[pkg+".Time"] = nullable(Node{Type: "date-time", Format: "string"})
+	}
+}
+
+// Register documents the values of T with n, for types whose JSON isn't their Go structure
+// and can't implement Noder, like third party types:
+//
+//	quick_schema.Register[decimal.Decimal](quick_schema.Node{Type: "decimal", Format: "string"})
+//
+// n's Format is the OpenAPI type and its Type the OpenAPI format, nullable types are a "pointer" node of their value
+func Register[T any](n Node) {
+	t := reflect.TypeOf(new(T)).Elem()
+	wellKnownMu.Lock()
+	defer wellKnownMu.Unlock()
+	wellKnown[typeKey(t)] = n
+}
+
+// wellKnownNode is the registered node of t, false if t isn't registered
+func wellKnownNode(t reflect.Type) (*Node, bool) {
+	wellKnownMu.RLock()
+	n, ok := wellKnown[typeKey(t)]
+	wellKnownMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	n.Package = t.PkgPath()
+	return &n, true
+}
+
+func typeKey(t reflect.Type) string {
+	if len(t.Name()) == 0 {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+func arrayOf(n Node) Node {
+	return Node{Format: "slice", Children: []Node{n}}
+}
+
+func nullable(n Node) Node {
+	return Node{Format: "pointer", Children: []Node{n}}
+}
@@ -0,0 +1,181 @@
+// Package schemadoc collects the doc comments of a package's types and struct fields,
+// and generates the file registering them as quick_schema descriptions
+package schemadoc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Package is the doc comments of a package
+type Package struct {
+	Name       string
+	ImportPath string
+	// doc comments by "Type" and "Type.Field"
+	Docs map[string]string
+}
+
+// Parse collects the doc comments of the exported structs and their exported fields declared in the package in dir,
+// test files and skip, the generated file, are left out
+func Parse(dir, skip string) (Package, error) {
+	p := Package{Docs: map[string]string{}}
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return p, err
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") || filepath.Base(name) == skip {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return p, err
+		}
+		if len(p.Name) > 0 && p.Name != f.Name.Name {
+			return p, fmt.Errorf("packages %s and %s in %s", p.Name, f.Name.Name, dir)
+		}
+		p.Name = f.Name.Name
+		collect(f, p.Docs)
+	}
+	if len(p.Name) == 0 {
+		return p, fmt.Errorf("no Go files in %s", dir)
+	}
+	p.ImportPath, err = importPath(dir)
+	return p, err
+}
+
+func collect(f *ast.File, docs map[string]string) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+			// a lone type's comment is the declaration's
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			// only structs have a schema of their own, unexported ones aren't part of the API
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !ts.Name.IsExported() {
+				continue
+			}
+			if d := text(doc); len(d) > 0 {
+				docs[ts.Name.Name] = d
+			}
+			for _, field := range st.Fields.List {
+				doc := field.Doc
+				if doc == nil {
+					doc = field.Comment
+				}
+				d := text(doc)
+				if len(d) == 0 {
+					continue
+				}
+				for _, n := range field.Names {
+					if n.IsExported() {
+						docs[ts.Name.Name+"."+n.Name] = d
+					}
+				}
+			}
+		}
+	}
+}
+
+// text is the first paragraph of a comment, its lines joined,
+// without the sentence introducing an example, which ends with a colon
+func text(c *ast.CommentGroup) string {
+	if c == nil {
+		return ""
+	}
+	p, _, _ := strings.Cut(c.Text(), "\n\n")
+	p = strings.Join(strings.Fields(p), " ")
+	if intro, ok := strings.CutSuffix(p, ":"); ok {
+		p = intro
+		if i := strings.LastIndex(intro, ". "); i >= 0 {
+			p = intro[:i+1]
+		}
+	}
+	return p
+}
+
+// importPath is the import path of the package in dir, from the module path in the closest go.mod
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		f, err := os.Open(filepath.Join(root, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			module, err := modulePath(f)
+			if err != nil {
+				return "", err
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(module, filepath.ToSlash(rel)), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("no go.mod above %s", abs)
+		}
+	}
+}
+
+func modulePath(r io.Reader) (string, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if m, ok := strings.CutPrefix(strings.TrimSpace(s.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(m), `"`), nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("go.mod has no module directive")
+}
+
+// Generate writes the Go file registering p's doc comments
+func Generate(w io.Writer, p Package) error {
+	keys := make([]string, 0, len(p.Docs))
+	for k := range p.Docs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by schemadoc. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", p.Name)
+	buf.WriteString("import \"github.com/pindamonhangaba/apiculi/quick_schema\"\n\n")
+	buf.WriteString("func init() {\n")
+	fmt.Fprintf(buf, "quick_schema.RegisterDescriptions(%q, map[string]string{\n", p.ImportPath)
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%q: %q,\n", k, p.Docs[k])
+	}
+	buf.WriteString("})\n}\n")
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
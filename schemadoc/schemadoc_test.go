@@ -0,0 +1,104 @@
+package schemadoc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `package shop
+
+// Order is a purchase
+// of some products.
+//
+// Orders are immutable.
+type Order struct {
+	// Unique identifier
+	ID string ` + "`json:\"id\"`" + `
+	Total float64 // In cents
+	Items []Item
+	note  string // internal
+}
+
+type (
+	// Item is a product in an order
+	Item struct {
+		SKU string // Stock keeping unit
+	}
+	// Status is not a struct
+	Status string
+)
+
+// Coupon is a discount.
+// Codes are declared like:
+//
+//	Coupon{Code: "SALE"}
+type Coupon struct {
+	Code string
+}
+
+// cart isn't exported
+type cart struct {
+	// Items in the cart
+	Items []Item
+}
+`
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":            "module example.com/store\n\ngo 1.22\n",
+		"shop/shop.go":      source,
+		"shop/shop_test.go": "package shop\n\n// Fixture is a test type\ntype Fixture struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := Parse(filepath.Join(dir, "shop"), "schemadoc_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "shop" || p.ImportPath != "example.com/store/shop" {
+		t.Errorf("unexpected package %s %s", p.Name, p.ImportPath)
+	}
+	expected := map[string]string{
+		"Order":       "Order is a purchase of some products.",
+		"Order.ID":    "Unique identifier",
+		"Order.Total": "In cents",
+		"Item":        "Item is a product in an order",
+		"Item.SKU":    "Stock keeping unit",
+		"Coupon":      "Coupon is a discount.",
+	}
+	if len(p.Docs) != len(expected) {
+		t.Errorf("expected %d docs, got %v", len(expected), p.Docs)
+	}
+	for k, d := range expected {
+		if p.Docs[k] != d {
+			t.Errorf("%s: expected %q, got %q", k, d, p.Docs[k])
+		}
+	}
+
+	b := &bytes.Buffer{}
+	if err := Generate(b, p); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"// Code generated by schemadoc. DO NOT EDIT.",
+		"package shop",
+		`quick_schema.RegisterDescriptions("example.com/store/shop", map[string]string{`,
+		`"Order.Total": "In cents",`,
+	} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("missing %s in:\n%s", s, b.String())
+		}
+	}
+}
@@ -0,0 +1,475 @@
+// Package tsgen generates TypeScript type definitions and a fetch based client from an OpenAPI document
+package tsgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var (
+	identRgx    = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	pathParmRgx = regexp.MustCompile(`{([^}]+)}`)
+)
+
+// Generate writes an interface, or a type alias, for every components/schemas entry of t,
+// and a createClient function returning one function per operation, named by its operationId.
+// t is an OpenAPI 3.0 document, endpoint.OpenAPI.T is one whatever the version set with SetVersion
+func Generate(w io.Writer, t *openapi3.T) error {
+	if strings.HasPrefix(t.OpenAPI, "3.1") {
+		return fmt.Errorf("OpenAPI %s documents are not supported, only 3.0 ones", t.OpenAPI)
+	}
+	g := newGenerator(t)
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by tsgen. DO NOT EDIT.\n\n")
+	g.writeSchemas(buf)
+	if err := g.writeClient(buf); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type generator struct {
+	t *openapi3.T
+	// TypeScript names of the components/schemas entries
+	names   map[string]string
+	schemas map[*openapi3.Schema]string
+}
+
+func newGenerator(t *openapi3.T) *generator {
+	g := &generator{
+		t:       t,
+		names:   map[string]string{},
+		schemas: map[*openapi3.Schema]string{},
+	}
+	if t.Components == nil {
+		return g
+	}
+	taken := map[string]bool{}
+	for _, n := range sortedKeys(t.Components.Schemas) {
+		name := TypeName(n)
+		for i := 2; taken[name]; i++ {
+			name = TypeName(n) + strconv.Itoa(i)
+		}
+		taken[name] = true
+		g.names[n] = name
+		if s := t.Components.Schemas[n]; s != nil && s.Value != nil {
+			g.schemas[s.Value] = name
+		}
+	}
+	return g
+}
+
+// TypeName turns a schema name into a TypeScript identifier, package paths are dropped, underscores are camel-cased
+// and generic arguments are appended: "github.com/org/pkg.Page[github.com/org/pkg.Item]" -> "PageItem", "user_profile" -> "UserProfile"
+func TypeName(name string) string {
+	base, args, _ := strings.Cut(name, "[")
+	if i := strings.LastIndexAny(base, "/."); i >= 0 && i < len(base)-1 {
+		base = base[i+1:]
+	}
+	words := strings.Split(base, "_")
+	if len(args) > 0 {
+		for _, a := range splitArgs(strings.TrimSuffix(args, "]")) {
+			words = append(words, TypeName(a))
+		}
+	}
+	var b strings.Builder
+	for _, w := range words {
+		for i, r := range w {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				continue
+			}
+			if i == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "T" + b.String()
+	}
+	return b.String()
+}
+
+// splitArgs splits generic arguments on top level commas
+func splitArgs(s string) []string {
+	args := []string{}
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, s[start:])
+}
+
+func (g *generator) writeSchemas(w *bytes.Buffer) {
+	if g.t.Components == nil {
+		return
+	}
+	for _, n := range sortedKeys(g.t.Components.Schemas) {
+		ref := g.t.Components.Schemas[n]
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		s := ref.Value
+		writeComment(w, "", s.Description)
+		if s.Type == "object" && len(s.Properties) > 0 && !s.Nullable {
+			fmt.Fprintf(w, "export interface %s ", g.names[n])
+			g.writeObject(w, s, "")
+			w.WriteString("\n\n")
+			continue
+		}
+		fmt.Fprintf(w, "export type %s = %s;\n\n", g.names[n], g.tsType(ref, ""))
+	}
+}
+
+func (g *generator) writeObject(w *bytes.Buffer, s *openapi3.Schema, indent string) {
+	w.WriteString("{\n")
+	for _, p := range sortedKeys(s.Properties) {
+		writeComment(w, indent+"  ", s.Properties[p].Value.Description)
+		opt := "?"
+		for _, r := range s.Required {
+			if r == p {
+				opt = ""
+			}
+		}
+		fmt.Fprintf(w, "%s  %s%s: %s;\n", indent, propName(p), opt, g.tsType(s.Properties[p], indent+"  "))
+	}
+	w.WriteString(indent + "}")
+}
+
+// tsType is the TypeScript type of a schema, components are referenced by name
+func (g *generator) tsType(ref *openapi3.SchemaRef, indent string) string {
+	if ref == nil {
+		return "unknown"
+	}
+	if len(ref.Ref) > 0 {
+		n := strings.TrimPrefix(ref.Ref, "#/components/schemas/")
+		if name, ok := g.names[n]; ok {
+			return name
+		}
+	}
+	s := ref.Value
+	if s == nil {
+		return "unknown"
+	}
+	t := g.valueType(s, indent)
+	if s.Nullable && t != "unknown" {
+		t += " | null"
+	}
+	return t
+}
+
+func (g *generator) valueType(s *openapi3.Schema, indent string) string {
+	// inline copies of components, e.g. the root schema of a response
+	if s.Type == "object" {
+		if name, ok := g.schemas[s]; ok && indent != "" {
+			return name
+		}
+		if name, ok := g.names[s.Title]; ok && indent != "" {
+			return name
+		}
+	}
+	if len(s.Enum) > 0 {
+		vals := []string{}
+		for _, e := range s.Enum {
+			vals = append(vals, literal(e))
+		}
+		return strings.Join(vals, " | ")
+	}
+	for _, of := range []struct {
+		refs openapi3.SchemaRefs
+		sep  string
+	}{{s.OneOf, " | "}, {s.AnyOf, " | "}, {s.AllOf, " & "}} {
+		if len(of.refs) > 0 {
+			types := []string{}
+			for _, r := range of.refs {
+				types = append(types, g.tsType(r, indent))
+			}
+			return "(" + strings.Join(types, of.sep) + ")"
+		}
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "binary" {
+			return "Blob"
+		}
+		return "string"
+	case "number", "integer":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array", "slice":
+		it := g.tsType(s.Items, indent)
+		if strings.ContainsAny(it, " |&") {
+			it = "(" + it + ")"
+		}
+		return it + "[]"
+	case "object":
+		if len(s.Properties) > 0 {
+			b := &bytes.Buffer{}
+			g.writeObject(b, s, indent)
+			return b.String()
+		}
+		if ap := s.AdditionalProperties.Schema; ap != nil {
+			return "Record<string, " + g.tsType(ap, indent) + ">"
+		}
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+type operation struct {
+	method string
+	path   string
+	op     *openapi3.Operation
+}
+
+func (g *generator) operations() []operation {
+	ops := []operation{}
+	for _, path := range sortedKeys(g.t.Paths) {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if op := g.t.Paths[path].GetOperation(method); op != nil {
+				ops = append(ops, operation{method: method, path: path, op: op})
+			}
+		}
+	}
+	return ops
+}
+
+func (g *generator) writeClient(w *bytes.Buffer) error {
+	w.WriteString(clientRuntime)
+	w.WriteString("export function createClient(config: ClientConfig = {}) {\n  return {\n")
+	seen := map[string]bool{}
+	for _, o := range g.operations() {
+		id := o.op.OperationID
+		if len(id) == 0 || !identRgx.MatchString(id) {
+			return fmt.Errorf("operation %s %s has an invalid operationId %q", o.method, o.path, id)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicated operationId %q", id)
+		}
+		seen[id] = true
+		g.writeOperation(w, o)
+	}
+	w.WriteString("  };\n}\n")
+	return nil
+}
+
+func (g *generator) writeOperation(w *bytes.Buffer, o operation) {
+	const indent = "      "
+	groups := map[string][]*openapi3.Parameter{}
+	for _, p := range o.op.Parameters {
+		if p.Value != nil {
+			groups[p.Value.In] = append(groups[p.Value.In], p.Value)
+		}
+	}
+	input := []string{}
+	// cookies are left to the browser
+	for _, in := range []string{openapi3.ParameterInPath, openapi3.ParameterInQuery, openapi3.ParameterInHeader} {
+		params := groups[in]
+		if len(params) == 0 {
+			continue
+		}
+		fields := []string{}
+		required := false
+		for _, p := range params {
+			opt := "?"
+			if p.Required {
+				opt, required = "", true
+			}
+			fields = append(fields, fmt.Sprintf("%s  %s%s: %s;\n", indent, propName(p.Name), opt, g.tsType(p.Schema, indent+"  ")))
+		}
+		opt := "?"
+		if required {
+			opt = ""
+		}
+		input = append(input, fmt.Sprintf("%s%s%s: {\n%s%s};\n", indent, in, opt, strings.Join(fields, ""), indent))
+	}
+	bodyType, multipart := "", false
+	if rb := o.op.RequestBody; rb != nil && rb.Value != nil {
+		mt := rb.Value.Content.Get("application/json")
+		if mt == nil {
+			mt, multipart = rb.Value.Content.Get("multipart/form-data"), true
+		}
+		if mt != nil {
+			bodyType = g.tsType(mt.Schema, indent)
+			input = append(input, fmt.Sprintf("%sbody: %s;\n", indent, bodyType))
+		}
+	}
+
+	writeComment(w, "    ", strings.TrimSpace(o.op.Summary+"\n\n"+o.op.Description))
+	arg := ""
+	if len(input) > 0 {
+		arg = "input: {\n" + strings.Join(input, "") + "    }, "
+	}
+	fmt.Fprintf(w, "    %s: (%sinit?: RequestInit): Promise<%s> =>\n", o.op.OperationID, arg, g.responseType(o.op))
+
+	path := pathParmRgx.ReplaceAllStringFunc(o.path, func(m string) string {
+		return "${encodeURIComponent(String(input.path[" + strconv.Quote(m[1:len(m)-1]) + "]))}"
+	})
+	opt := func(in string) string {
+		if len(groups[in]) > 0 {
+			return "input." + in
+		}
+		return "undefined"
+	}
+	body := "undefined"
+	if len(bodyType) > 0 {
+		body = "input.body"
+		if multipart {
+			body = "formData(input.body)"
+		}
+	}
+	fmt.Fprintf(w, "      request(config, %q, `%s`, %s, %s, %s, init),\n", o.method, path, opt(openapi3.ParameterInQuery), opt(openapi3.ParameterInHeader), body)
+}
+
+// responseType is the type of the operation's success response
+func (g *generator) responseType(op *openapi3.Operation) string {
+	for _, code := range sortedKeys(op.Responses) {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		r := op.Responses[code].Value
+		if r == nil || r.Content == nil {
+			return "void"
+		}
+		if mt := r.Content.Get("application/json"); mt != nil {
+			return g.tsType(mt.Schema, "    ")
+		}
+	}
+	return "void"
+}
+
+func writeComment(w *bytes.Buffer, indent, text string) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return
+	}
+	w.WriteString(indent + "/**\n")
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.ReplaceAll(strings.TrimSpace(l), "*/", "*\\/")
+		w.WriteString(strings.TrimRight(indent+" * "+l, " ") + "\n")
+	}
+	w.WriteString(indent + " */\n")
+}
+
+func propName(n string) string {
+	if identRgx.MatchString(n) {
+		return n
+	}
+	return strconv.Quote(n)
+}
+
+func literal(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const clientRuntime = `export interface ClientConfig {
+  /** URL the operations' paths are appended to */
+  baseURL?: string;
+  fetch?: typeof fetch;
+  /** Applied to every request, e.g. to set the Authorization header */
+  init?: RequestInit;
+}
+
+export interface ErrorDetail {
+  domain: string;
+  reason: string;
+  message: string;
+  location?: string;
+  locationType?: string;
+  extendedHelp?: string;
+  sendReport?: string;
+}
+
+/** A JSONC error response */
+export class APIError extends Error {
+  constructor(
+    public status: number,
+    public code: number,
+    message: string,
+    public errors: ErrorDetail[] = [],
+  ) {
+    super(message);
+  }
+}
+
+type Params = Record<string, unknown> | undefined;
+
+function formData(body: Record<string, unknown>): FormData {
+  const data = new FormData();
+  for (const [k, v] of Object.entries(body)) {
+    for (const item of Array.isArray(v) ? v : [v]) {
+      if (item === undefined || item === null) continue;
+      data.append(k, item instanceof Blob ? item : typeof item === "object" ? JSON.stringify(item) : String(item));
+    }
+  }
+  return data;
+}
+
+async function request<T>(config: ClientConfig, method: string, path: string, query: Params, header: Params, body: unknown, init?: RequestInit): Promise<T> {
+  const search = new URLSearchParams();
+  for (const [k, v] of Object.entries(query ?? {})) {
+    for (const item of Array.isArray(v) ? v : [v]) {
+      if (item === undefined || item === null) continue;
+      search.append(k, typeof item === "object" ? JSON.stringify(item) : String(item));
+    }
+  }
+  const headers = new Headers(config.init?.headers);
+  new Headers(init?.headers).forEach((v, k) => headers.set(k, v));
+  for (const [k, v] of Object.entries(header ?? {})) {
+    if (v !== undefined && v !== null) headers.set(k, String(v));
+  }
+  headers.set("Accept", "application/json");
+  if (body !== undefined && !(body instanceof FormData)) {
+    headers.set("Content-Type", "application/json");
+    body = JSON.stringify(body);
+  }
+  const qs = search.toString();
+  const res = await (config.fetch ?? fetch)((config.baseURL ?? "") + path + (qs ? "?" + qs : ""), {
+    ...config.init,
+    ...init,
+    method,
+    headers,
+    body: body as BodyInit | undefined,
+  });
+  const text = await res.text();
+  const data = text ? JSON.parse(text) : undefined;
+  if (!res.ok) {
+    throw new APIError(res.status, data?.error?.code ?? res.status, data?.error?.message ?? res.statusText, data?.error?.errors);
+  }
+  return data as T;
+}
+
+`
@@ -0,0 +1,99 @@
+package tsgen
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pindamonhangaba/apiculi/endpoint"
+)
+
+type collection struct {
+	ID   int64    `json:"id"`
+	Name string   `json:"name" description:"Name of the collection"`
+	Kind string   `json:"kind" validate:"enum=public|private"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type getParams struct {
+	ID      int64  `json:"id"`
+	IfMatch string `json:"If-Match,omitempty" in:"header"`
+}
+
+type listQuery struct {
+	Context string `json:"context,omitempty"`
+	Page    int64  `json:"page,omitempty"`
+}
+
+type upload struct {
+	Title  string        `json:"title"`
+	Avatar endpoint.File `json:"avatar"`
+}
+
+func TestTypeName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"github.com/pindamonhangaba/apiculi/endpoint.DataResponse[github.com/pindamonhangaba/apiculi/endpoint.SingleItemData[string]]": "DataResponseSingleItemDataString",
+		"github.com/org/pkg.Pair[int,github.com/org/pkg.Item]":                                                                         "PairIntItem",
+		"name":         "Name",
+		"1thing":       "T1thing",
+		"user_profile": "UserProfile",
+		"Order_v2":     "OrderV2",
+		"pkg.Order_v2": "OrderV2",
+	} {
+		if n := TypeName(name); n != expected {
+			t.Errorf("TypeName(%q) = %q, expected %q", name, n, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	oapi := endpoint.NewOpenAPI("API", "v1")
+	endpoint.Gorilla(
+		endpoint.Get("/api/collection/{id}"),
+		oapi.Route("collection.Get", `Get one collection`),
+		func(in endpoint.EndpointInput[any, getParams, listQuery, any]) (res endpoint.DataResponse[endpoint.SingleItemData[collection]], err error) {
+			return res, nil
+		},
+	)
+	endpoint.Gorilla(
+		endpoint.Post("/api/collection").WithStatus(http.StatusCreated),
+		oapi.Route("collection.Create", `Create a collection`),
+		func(in endpoint.EndpointInput[any, any, any, collection]) (res endpoint.DataResponse[endpoint.SingleItemData[collection]], err error) {
+			return res, nil
+		},
+	)
+	endpoint.Gorilla(
+		endpoint.Post("/api/upload").WithStatus(http.StatusNoContent),
+		oapi.Route("upload", `Upload an avatar`),
+		func(in endpoint.EndpointInput[any, any, any, upload]) (res endpoint.DataResponse[endpoint.SingleItemData[string]], err error) {
+			return res, nil
+		},
+	)
+
+	b := &bytes.Buffer{}
+	if err := Generate(b, oapi.T()); err != nil {
+		t.Fatal(err)
+	}
+	ts := b.String()
+	for _, s := range []string{
+		"export interface Collection {\n  id: number;\n  kind: \"public\" | \"private\";\n  /**\n   * Name of the collection\n   */\n  name: string;\n  tags?: string[] | null;\n}",
+		"export interface SingleItemDataCollection {\n  item: Collection;\n",
+		"collectionGet: (input: {\n      path: {\n        id: number;\n      };\n      query?: {\n        context?: string | null;\n        page?: number | null;\n      };\n      header?: {\n        \"If-Match\"?: string | null;\n      };\n    }, init?: RequestInit): Promise<DataResponseSingleItemDataCollection> =>",
+		"request(config, \"GET\", `/api/collection/${encodeURIComponent(String(input.path[\"id\"]))}`, input.query, input.header, undefined, init),",
+		"request(config, \"POST\", `/api/collection`, undefined, undefined, input.body, init),",
+		"export interface Upload {\n  avatar: Blob;\n  title: string;\n}",
+		"upload: (input: {\n      body: Upload;\n    }, init?: RequestInit): Promise<void> =>",
+		"formData(input.body)",
+	} {
+		if !strings.Contains(ts, s) {
+			t.Errorf("expected\n%s", s)
+		}
+	}
+
+	doc := oapi.T()
+	doc.OpenAPI = endpoint.OpenAPI31
+	if err := Generate(&bytes.Buffer{}, doc); err == nil {
+		t.Error("OpenAPI 3.1 document accepted")
+	}
+}